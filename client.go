@@ -1,6 +1,7 @@
 package sqle
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
 	"log"
@@ -9,15 +10,55 @@ import (
 )
 
 type Client struct {
+	latency  int64 // moving average latency of queries in nanoseconds if it is a replica, it should be 64-bit aligned
+	inflight int64 // statements and transactions in flight
+
 	*sql.DB
 	sync.Mutex
 	_ noCopy
 
 	stmts      map[string]*Stmt
+	stmtsLRU   *list.List
 	stmtsMutex sync.Mutex
+	stmtStats  StmtStats
 
 	stmtMaxIdleTime time.Duration
+	stmtMaxSize     int
 	Index           int
+
+	hooks *hooks
+
+	replicas      []*Client
+	replicasMu    sync.RWMutex
+	replicaPolicy ReplicaPolicy
+	replica       int // 1-based index if it is a replica of Index
+	next          uint32
+
+	health   int32 // HealthState
+	failures int32 // consecutive ping failures
+
+	drained int32
+	stop    chan struct{} // it stops checkIdleStmt
+	reset   chan struct{} // it restarts checkIdleStmt with new stmtMaxIdleTime
+}
+
+// newClient creates a Client for the database of index, and starts to close its idle statements.
+func newClient(db *sql.DB, index int, hooks *hooks, stmtMaxIdleTime time.Duration, stmtMaxSize int) *Client {
+	c := &Client{
+		DB:              db,
+		Index:           index,
+		stmts:           make(map[string]*Stmt),
+		stmtsLRU:        list.New(),
+		stmtMaxIdleTime: stmtMaxIdleTime,
+		stmtMaxSize:     stmtMaxSize,
+		hooks:           hooks,
+		stop:            make(chan struct{}),
+		reset:           make(chan struct{}, 1),
+	}
+
+	go c.checkIdleStmt()
+
+	return c
 }
 
 func (db *Client) Query(query string, args ...any) (*Rows, error) {
@@ -25,6 +66,7 @@ func (db *Client) Query(query string, args ...any) (*Rows, error) {
 }
 
 func (db *Client) QueryBuilder(ctx context.Context, b *Builder) (*Rows, error) {
+	ctx = withRotate(ctx, b)
 	query, args, err := b.Build()
 	if err != nil {
 		return nil, err
@@ -34,6 +76,26 @@ func (db *Client) QueryBuilder(ctx context.Context, b *Builder) (*Rows, error) {
 }
 
 func (db *Client) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+
+	r := db.reader(ctx)
+	ctx, e := db.hooks.before(ctx, HookEvent{Op: OpQuery, Index: db.Index, Replica: r.replica, Query: query, Args: args})
+	now := time.Now()
+	rows, err := r.queryContext(ctx, query, args...)
+	r.observe(time.Since(now), err)
+	db.hooks.after(ctx, e, nil, err)
+	if err != nil {
+		db.release()
+		return nil, err
+	}
+
+	rows.release = db.release
+	return rows, nil
+}
+
+func (db *Client) queryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
 	var rows *sql.Rows
 	var stmt *Stmt
 	var err error
@@ -64,6 +126,7 @@ func (db *Client) QueryRow(query string, args ...any) *Row {
 }
 
 func (db *Client) QueryRowBuilder(ctx context.Context, b *Builder) *Row {
+	ctx = withRotate(ctx, b)
 	query, args, err := b.Build()
 	if err != nil {
 		return &Row{
@@ -76,6 +139,25 @@ func (db *Client) QueryRowBuilder(ctx context.Context, b *Builder) *Row {
 }
 
 func (db *Client) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	if err := db.acquire(); err != nil {
+		return &Row{err: err, query: query}
+	}
+
+	c := db.reader(ctx)
+	ctx, e := db.hooks.before(ctx, HookEvent{Op: OpQuery, Index: db.Index, Replica: c.replica, Query: query, Args: args})
+	now := time.Now()
+	r := c.queryRowContext(ctx, query, args...)
+	c.observe(time.Since(now), r.err)
+	db.hooks.after(ctx, e, nil, r.err)
+	if r.err != nil {
+		db.release()
+	} else {
+		r.release = db.release
+	}
+	return r
+}
+
+func (db *Client) queryRowContext(ctx context.Context, query string, args ...any) *Row {
 	var rows *sql.Rows
 	var stmt *Stmt
 	var err error
@@ -113,6 +195,7 @@ func (db *Client) Exec(query string, args ...any) (sql.Result, error) {
 }
 
 func (db *Client) ExecBuilder(ctx context.Context, b *Builder) (sql.Result, error) {
+	ctx = withRotate(ctx, b)
 	query, args, err := b.Build()
 	if err != nil {
 		return nil, err
@@ -122,6 +205,18 @@ func (db *Client) ExecBuilder(ctx context.Context, b *Builder) (sql.Result, erro
 }
 
 func (db *Client) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	ctx, e := db.hooks.before(ctx, HookEvent{Op: OpExec, Index: db.Index, Query: query, Args: args})
+	result, err := db.execContext(ctx, query, args...)
+	db.hooks.after(ctx, e, result, err)
+	return result, err
+}
+
+func (db *Client) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	if len(args) > 0 {
 		stmt, err := db.prepareStmt(ctx, query)
 		if err != nil {
@@ -132,7 +227,7 @@ func (db *Client) ExecContext(ctx context.Context, query string, args ...any) (s
 
 		return stmt.ExecContext(ctx, args...)
 	}
-	return db.DB.ExecContext(context.Background(), query, args...)
+	return db.DB.ExecContext(ctx, query, args...)
 }
 
 func (db *Client) Begin(opts *sql.TxOptions) (*Tx, error) {
@@ -141,12 +236,19 @@ func (db *Client) Begin(opts *sql.TxOptions) (*Tx, error) {
 }
 
 func (db *Client) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+
+	ctx, e := db.hooks.before(ctx, HookEvent{Op: OpTx, Index: db.Index, InTx: true, Query: "BEGIN"})
 	tx, err := db.DB.BeginTx(ctx, opts)
 	if err != nil {
+		db.hooks.after(ctx, e, nil, err)
+		db.release()
 		return nil, err
 	}
 
-	return &Tx{Tx: tx, stmts: make(map[string]*sql.Stmt)}, nil
+	return &Tx{Tx: tx, stmts: make(map[string]*sql.Stmt), index: db.Index, hooks: db.hooks, ctx: ctx, event: e, release: db.release}, nil
 }
 
 func (db *Client) Transaction(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
@@ -155,7 +257,8 @@ func (db *Client) Transaction(ctx context.Context, opts *sql.TxOptions, fn func(
 		return err
 	}
 
-	err = fn(ctx, tx)
+	// queries in fn are traced as children of the transaction
+	err = fn(tx.ctx, tx)
 	defer func() {
 		if err != nil {
 			if e := tx.Rollback(); e != nil {
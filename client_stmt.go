@@ -1,6 +1,7 @@
 package sqle
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
 	"sync"
@@ -11,24 +12,95 @@ type Stmt struct {
 	*sql.Stmt
 	mu       sync.Mutex
 	lastUsed time.Time
-	isUsing  bool
+	using    int // number of queries that are using it
+
+	query string
+	elem  *list.Element // position in lru of Client
 }
 
 func (s *Stmt) Reuse() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.isUsing = false
+	if s.using > 0 {
+		s.using--
+	}
+}
+
+// StmtStats contains statistics of the prepared statement cache of a Client.
+type StmtStats struct {
+	Open        int   // number of cached statements
+	Hits        int64 // number of queries that reuse a cached statement
+	Misses      int64 // number of queries that prepare a new statement
+	Evictions   int64 // number of statements that are closed as the cache is full
+	Expirations int64 // number of statements that are closed as they are idle for StmtMaxIdleTime
+}
+
+// SetStmtMaxIdleTime sets the maximum amount of time a prepared statement may be idle before it is closed. It is
+// ignored if d <= 0. It is applied on replicas too.
+func (db *Client) SetStmtMaxIdleTime(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	db.stmtsMutex.Lock()
+	defer db.stmtsMutex.Unlock()
+
+	db.stmtMaxIdleTime = d
+
+	select {
+	case db.reset <- struct{}{}:
+	default:
+	}
+
+	for _, r := range db.getReplicas() {
+		r.SetStmtMaxIdleTime(d)
+	}
+}
+
+// SetStmtMaxSize sets the maximum number of prepared statements that are cached. The least recently used statement
+// is closed once the cache is full. If n <= 0, there is no limit. It is applied on replicas too.
+func (db *Client) SetStmtMaxSize(n int) {
+	db.stmtsMutex.Lock()
+	defer db.stmtsMutex.Unlock()
+
+	db.stmtMaxSize = n
+	db.evictStmts()
+
+	for _, r := range db.getReplicas() {
+		r.SetStmtMaxSize(n)
+	}
+}
+
+// StmtStats returns statistics of the prepared statement cache.
+func (db *Client) StmtStats() StmtStats {
+	db.stmtsMutex.Lock()
+	defer db.stmtsMutex.Unlock()
+
+	s := db.stmtStats
+	s.Open = len(db.stmts)
+	return s
 }
 
 func (db *Client) prepareStmt(ctx context.Context, query string) (*Stmt, error) {
 	db.stmtsMutex.Lock()
 	defer db.stmtsMutex.Unlock()
+
+	if db.stmtsLRU == nil {
+		db.stmtsLRU = list.New()
+	}
+
 	s, ok := db.stmts[query]
 
 	if ok {
+		s.mu.Lock()
 		s.lastUsed = time.Now()
-		s.isUsing = true
+		s.using++
+		s.mu.Unlock()
+
+		db.stmtsLRU.MoveToFront(s.elem)
+		db.stmtStats.Hits++
+		setStmtCached(ctx, true)
 		return s, nil
 	}
 
@@ -40,24 +112,61 @@ func (db *Client) prepareStmt(ctx context.Context, query string) (*Stmt, error)
 	s = &Stmt{
 		Stmt:     stmt,
 		lastUsed: time.Now(),
-		isUsing:  true,
+		using:    1,
+		query:    query,
 	}
 
+	s.elem = db.stmtsLRU.PushFront(s)
 	db.stmts[query] = s
+	db.stmtStats.Misses++
+	setStmtCached(ctx, false)
+
+	db.evictStmts()
 
 	return s, nil
 }
 
+// evictStmts closes least recently used statements that are not in using until the cache is not full.
+func (db *Client) evictStmts() {
+	if db.stmtMaxSize <= 0 || db.stmtsLRU == nil {
+		return
+	}
+
+	e := db.stmtsLRU.Back()
+	for len(db.stmts) > db.stmtMaxSize && e != nil {
+		prev := e.Prev()
+		s := e.Value.(*Stmt)
+
+		s.mu.Lock()
+		if s.using == 0 {
+			db.removeStmt(s)
+			db.stmtStats.Evictions++
+		}
+		s.mu.Unlock()
+
+		e = prev
+	}
+}
+
+// removeStmt removes s from cache and closes it once its rows are closed.
+func (db *Client) removeStmt(s *Stmt) {
+	delete(db.stmts, s.query)
+	if s.elem != nil {
+		db.stmtsLRU.Remove(s.elem)
+	}
+	go s.Stmt.Close() //nolint: errcheck
+}
+
 func (db *Client) closeStaleStmt() {
 	db.stmtsMutex.Lock()
 	defer db.stmtsMutex.Unlock()
 
 	lastActive := time.Now().Add(-db.stmtMaxIdleTime)
-	for k, s := range db.stmts {
+	for _, s := range db.stmts {
 		s.mu.Lock()
-		if !s.isUsing && s.lastUsed.Before(lastActive) {
-			delete(db.stmts, k)
-			go s.Stmt.Close() //nolint: errcheck
+		if s.using == 0 && s.lastUsed.Before(lastActive) {
+			db.removeStmt(s)
+			db.stmtStats.Expirations++
 		}
 		s.mu.Unlock()
 	}
@@ -65,11 +174,25 @@ func (db *Client) closeStaleStmt() {
 }
 
 func (db *Client) checkIdleStmt() {
-	delay := time.NewTicker(db.stmtMaxIdleTime)
-	defer delay.Stop()
-
 	for {
-		<-delay.C
+		db.stmtsMutex.Lock()
+		d := db.stmtMaxIdleTime
+		db.stmtsMutex.Unlock()
+
+		if d <= 0 {
+			d = 3 * time.Minute
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-db.stop:
+			timer.Stop()
+			return
+		case <-db.reset:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
 
 		db.closeStaleStmt()
 	}
@@ -1,7 +1,11 @@
 package sqle
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -129,13 +133,13 @@ func TestStmt(t *testing.T) {
 				s, ok := db.stmts[q]
 				db.stmtsMutex.Unlock()
 				require.True(t, ok)
-				require.False(t, s.isUsing)
+				require.Equal(t, 0, s.using)
 
 				time.Sleep(2 * time.Second)
 				db.closeStaleStmt()
 
 				// stmt should be closed and released
-				require.False(t, s.isUsing)
+				require.Equal(t, 0, s.using)
 
 				s, ok = db.stmts[q]
 				require.False(t, ok)
@@ -153,7 +157,7 @@ func TestStmt(t *testing.T) {
 
 				s, ok := db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				time.Sleep(2 * time.Second)
 				db.closeStaleStmt()
@@ -161,12 +165,12 @@ func TestStmt(t *testing.T) {
 				// stmt that is in using should not be closed
 				s, ok = db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				rows.Scan(&id) // nolint: errcheck
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 				rows.Close()
-				require.False(t, s.isUsing)
+				require.Equal(t, 0, s.using)
 
 				db.closeStaleStmt()
 
@@ -189,7 +193,7 @@ func TestStmt(t *testing.T) {
 
 				s, ok := db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				time.Sleep(2 * time.Second)
 				db.closeStaleStmt()
@@ -197,10 +201,10 @@ func TestStmt(t *testing.T) {
 				// stmt that is in using should not be closed
 				s, ok = db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				rows.Bind(&r) // nolint: errcheck
-				require.False(t, s.isUsing)
+				require.Equal(t, 0, s.using)
 
 				db.closeStaleStmt()
 
@@ -220,7 +224,7 @@ func TestStmt(t *testing.T) {
 
 				s, ok := db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				time.Sleep(2 * time.Second)
 				db.closeStaleStmt()
@@ -228,10 +232,10 @@ func TestStmt(t *testing.T) {
 				// stmt that is in using should not be closed
 				s, ok = db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				row.Scan(&id) // nolint: errcheck
-				require.False(t, s.isUsing)
+				require.Equal(t, 0, s.using)
 
 				db.closeStaleStmt()
 
@@ -253,7 +257,7 @@ func TestStmt(t *testing.T) {
 
 				s, ok := db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				time.Sleep(2 * time.Second)
 				db.closeStaleStmt()
@@ -261,10 +265,10 @@ func TestStmt(t *testing.T) {
 				// stmt that is in using should not be closed
 				s, ok = db.stmts[q]
 				require.True(t, ok)
-				require.True(t, s.isUsing)
+				require.Equal(t, 1, s.using)
 
 				row.Bind(&r) // nolint: errcheck
-				require.False(t, s.isUsing)
+				require.Equal(t, 0, s.using)
 
 				db.closeStaleStmt()
 
@@ -282,3 +286,164 @@ func TestStmt(t *testing.T) {
 		})
 	}
 }
+
+func TestStmtCache(t *testing.T) {
+	d := createSQLite3()
+	d.SetMaxOpenConns(1)
+
+	_, err := d.Exec("CREATE TABLE `stmt_cache` (`id` int, PRIMARY KEY (`id`))")
+	require.NoError(t, err)
+
+	db := Open(d)
+	db.SetStmtMaxSize(2)
+
+	q1 := "SELECT id FROM stmt_cache WHERE id = ?"
+	q2 := "SELECT id FROM stmt_cache WHERE id > ?"
+	q3 := "SELECT id FROM stmt_cache WHERE id < ?"
+
+	var id int
+	for _, q := range []string{q1, q2, q1} {
+		err = db.QueryRow(q, 1).Scan(&id)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+
+	require.Equal(t, StmtStats{Open: 2, Hits: 1, Misses: 2}, db.StmtStats())
+
+	// q2 is the least recently used statement
+	err = db.QueryRow(q3, 1).Scan(&id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Equal(t, StmtStats{Open: 2, Hits: 1, Misses: 3, Evictions: 1}, db.StmtStats())
+
+	db.stmtsMutex.Lock()
+	_, ok := db.stmts[q2]
+	db.stmtsMutex.Unlock()
+	require.False(t, ok)
+
+	// statements in using are not evicted
+	rows, err := db.Query(q1, 1)
+	require.NoError(t, err)
+	db.SetStmtMaxSize(1)
+	require.Equal(t, 1, db.StmtStats().Open)
+
+	db.stmtsMutex.Lock()
+	_, ok = db.stmts[q1]
+	db.stmtsMutex.Unlock()
+	require.True(t, ok)
+	require.NoError(t, rows.Close())
+
+	// idle statements are expired
+	db.SetStmtMaxIdleTime(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	db.closeStaleStmt()
+	stats := db.StmtStats()
+	require.Equal(t, 0, stats.Open)
+	require.Equal(t, int64(1), stats.Expirations)
+
+	// databases that are added later inherit options of DB
+	db.Add(createSQLite3())
+	c := db.dbs[1]
+	require.Equal(t, 1, c.stmtMaxSize)
+	require.Equal(t, time.Millisecond, c.stmtMaxIdleTime)
+
+	// there is no limit if max size <= 0
+	db.SetStmtMaxSize(0)
+	for i := 0; i < 3; i++ {
+		err = db.QueryRow(fmt.Sprintf("SELECT id FROM stmt_cache WHERE id = ? AND %d = %d", i, i), 1).Scan(&id)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+	require.Equal(t, 3, db.StmtStats().Open)
+
+	// max idle time <= 0 is ignored
+	db.SetStmtMaxIdleTime(0)
+	db.SetStmtMaxIdleTime(-time.Second)
+	require.Equal(t, time.Millisecond, db.stmtMaxIdleTime)
+	require.Equal(t, time.Millisecond, c.stmtMaxIdleTime)
+}
+
+func TestStmtMaxIdleTime(t *testing.T) {
+	d := createSQLite3()
+	d.SetMaxOpenConns(1)
+
+	stmtMaxIdleTime := StmtMaxIdleTime
+	StmtMaxIdleTime = time.Hour
+	db := Open(d)
+	StmtMaxIdleTime = stmtMaxIdleTime
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT ?", 1).Scan(&n))
+	require.Equal(t, 1, db.StmtStats().Open)
+
+	// new max idle time takes effect immediately
+	db.SetStmtMaxIdleTime(10 * time.Millisecond)
+	require.Eventually(t, func() bool {
+		return db.StmtStats().Open == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStmtCacheConcurrent(t *testing.T) {
+	d, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "stmt.db"))
+	require.NoError(t, err)
+
+	db := Open(d)
+	db.SetStmtMaxSize(1)
+
+	// a statement is shared by two queries, it is not evicted until both of them are finished
+	s1, err := db.prepareStmt(context.TODO(), "SELECT ?")
+	require.NoError(t, err)
+	s2, err := db.prepareStmt(context.TODO(), "SELECT ?")
+	require.NoError(t, err)
+	require.Same(t, s1, s2)
+
+	s1.Reuse()
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT ? + 1", 1).Scan(&n))
+	require.Equal(t, 2, n)
+
+	require.NoError(t, s2.QueryRowContext(context.TODO(), 1).Scan(&n))
+	require.Equal(t, 1, n)
+	s2.Reuse()
+
+	// closing rows or row again doesn't release the statement of other queries
+	s3, err := db.prepareStmt(context.TODO(), "SELECT ?")
+	require.NoError(t, err)
+
+	rows, err := db.Query("SELECT ?", 1)
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&n))
+	require.NoError(t, rows.Close())
+	require.NoError(t, rows.Close())
+
+	row := db.QueryRow("SELECT ?", 1)
+	require.NoError(t, row.Scan(&n))
+	require.NoError(t, row.Close())
+
+	s3.mu.Lock()
+	require.Equal(t, 1, s3.using)
+	s3.mu.Unlock()
+	s3.Reuse()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var v int
+				if err := db.QueryRow(fmt.Sprintf("SELECT ? + %d", (i+j)%3), j).Scan(&v); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,86 @@
+package sqle
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Close rejects new statements, stops checkIdleStmt, and closes cached statements, read replicas and the database.
+// Errors of them are joined. Rows and transactions should be closed before it is called, as closing a statement waits
+// for its rows.
+func (db *Client) Close() error {
+	errs := db.close()
+
+	for _, r := range db.getReplicas() {
+		if err := r.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("replica-%d: %w", r.replica, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// close rejects new statements, stops checkIdleStmt, and closes cached statements and the database without replicas.
+func (db *Client) close() []error {
+	atomic.StoreInt32(&db.drained, 1)
+
+	db.stmtsMutex.Lock()
+	db.stopCheckIdleStmt()
+
+	stmts := db.stmts
+	db.stmts = make(map[string]*Stmt)
+	if db.stmtsLRU != nil {
+		db.stmtsLRU.Init()
+	}
+	db.stmtsMutex.Unlock()
+
+	var errs []error
+	for _, s := range stmts {
+		if err := s.Stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := db.DB.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// Close stops health check, and closes all databases with their cached statements and read replicas, standbys that
+// have not been promoted, and databases that have been replaced by their standbys. Errors of them are joined.
+func (db *DB) Close() error {
+	db.StopHealthCheck()
+
+	db.mu.Lock()
+	clients := db.dbs
+	standbys := db.standbys
+	retired := db.retired
+	db.standbys = nil
+	db.retired = nil
+	db.mu.Unlock()
+
+	var errs []error
+	for _, c := range clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sqle: db-%d: %w", c.Index, err))
+		}
+	}
+
+	// replicas of them are moved to their standbys, and they are closed above
+	for _, c := range retired {
+		if err := errors.Join(c.close()...); err != nil {
+			errs = append(errs, fmt.Errorf("sqle: retired-db-%d: %w", c.Index, err))
+		}
+	}
+
+	for i, d := range standbys {
+		if err := d.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sqle: standby-%d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
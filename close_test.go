@@ -0,0 +1,95 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errCloseFailed = errors.New("close failed")
+
+// closeErrConnector is a connector that fails to close.
+type closeErrConnector struct{}
+
+func (closeErrConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("not supported")
+}
+
+func (closeErrConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (closeErrConnector) Close() error {
+	return errCloseFailed
+}
+
+func TestClose(t *testing.T) {
+	d0 := createSQLite3()
+	d0.SetMaxOpenConns(1)
+	r0 := createSQLite3()
+	standby := createSQLite3()
+
+	db := Open(d0, sql.OpenDB(closeErrConnector{}))
+	db.AddReplica(0, r0)
+	db.SetStandby(0, standby)
+	db.StartHealthCheck(HealthCheck{})
+
+	_, err := db.Exec("CREATE TABLE nodes (id int)")
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, db.QueryRowContext(WithPrimary(context.TODO()), "SELECT count(*) FROM nodes WHERE id > ?", 0).Scan(&n))
+
+	stmt := db.stmts["SELECT count(*) FROM nodes WHERE id > ?"]
+	require.NotNil(t, stmt)
+	c := db.Client
+	replica := c.getReplicas()[0]
+
+	err = db.Close()
+	require.ErrorIs(t, err, errCloseFailed)
+	require.Contains(t, err.Error(), "sqle: db-1: close failed")
+
+	// checkIdleStmt and health check are stopped
+	_, ok := <-c.stop
+	require.False(t, ok)
+	_, ok = <-replica.stop
+	require.False(t, ok)
+	require.Nil(t, db.healthStop)
+
+	// statements, replicas, standbys and databases are closed
+	require.Equal(t, 0, c.StmtStats().Open)
+	_, err = stmt.Exec(1)
+	require.Error(t, err)
+	require.Error(t, d0.Ping())
+	require.Error(t, r0.Ping())
+	require.Error(t, standby.Ping())
+
+	_, err = db.Exec("DELETE FROM nodes")
+	require.ErrorIs(t, err, ErrClientDrained)
+
+	// it is safe to close again
+	require.NoError(t, c.Close())
+}
+
+func TestCloseShouldClosePromotedDatabase(t *testing.T) {
+	d0 := createSQLite3()
+	r0 := createSQLite3()
+	standby := createSQLite3()
+
+	db := Open(d0)
+	db.AddReplica(0, r0)
+	db.SetStandby(0, standby)
+
+	old := db.dbs[0]
+	require.True(t, db.promote(old))
+	require.NotSame(t, old, db.dbs[0])
+
+	require.NoError(t, db.Close())
+	require.ErrorContains(t, d0.Ping(), "sql: database is closed")
+	require.ErrorContains(t, r0.Ping(), "sql: database is closed")
+	require.ErrorContains(t, standby.Ping(), "sql: database is closed")
+	require.Nil(t, db.retired)
+}
@@ -10,35 +10,48 @@ import (
 )
 
 var (
+	// StmtMaxIdleTime is the default maximum amount of time a prepared statement may be idle, it can be changed on
+	// each Client by SetStmtMaxIdleTime.
 	StmtMaxIdleTime = 3 * time.Minute
-	ErrMissingDHT   = errors.New("sqle: missing_dht")
+	// StmtMaxSize is the default maximum number of cached prepared statements of a Client, there is no limit if it
+	// is 0. it can be changed on each Client by SetStmtMaxSize.
+	StmtMaxSize = 0
+
+	ErrMissingDHT = errors.New("sqle: missing_dht")
 )
 
 // DB represents a database connection pool with sharding support.
 type DB struct {
+	// Client is the Client of database 0 when DB is opened. Statements and *sql.DB methods on DB follow Replace and
+	// standby promotion of database 0, but it and its DB field don't. Index is always 0.
 	*Client
 	_ noCopy //nolint: unused
 
 	mu   sync.RWMutex
 	dhts map[string]*shardid.DHT
 	dbs  []*Client
+
+	hooks *hooks
+
+	stmtMaxIdleTime time.Duration
+	stmtMaxSize     int
+
+	standbys   map[int]*sql.DB
+	retired    []*Client // databases that are replaced by their standbys, they are closed by Close
+	healthStop chan struct{}
 }
 
 // Open creates a new DB instance with the provided database connections.
 func Open(dbs ...*sql.DB) *DB {
 	d := &DB{
-		dhts: make(map[string]*shardid.DHT),
+		dhts:            make(map[string]*shardid.DHT),
+		hooks:           &hooks{},
+		stmtMaxIdleTime: StmtMaxIdleTime,
+		stmtMaxSize:     StmtMaxSize,
 	}
 
 	for i, db := range dbs {
-		ctx := &Client{
-			DB:              db,
-			Index:           i,
-			stmts:           make(map[string]*Stmt),
-			stmtMaxIdleTime: StmtMaxIdleTime,
-		}
-		d.dbs = append(d.dbs, ctx)
-		go ctx.checkIdleStmt()
+		d.dbs = append(d.dbs, newClient(db, i, d.hooks, d.stmtMaxIdleTime, d.stmtMaxSize))
 	}
 
 	d.Client = d.dbs[0]
@@ -48,23 +61,69 @@ func Open(dbs ...*sql.DB) *DB {
 
 // Add dynamically scales out the DB with new databases.
 func (db *DB) Add(dbs ...*sql.DB) {
-	db.Lock()
-	defer db.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
 	n := len(db.dbs)
 
 	for i, d := range dbs {
-		ctx := &Client{
-			DB:              d,
-			Index:           n + i,
-			stmts:           make(map[string]*Stmt),
-			stmtMaxIdleTime: StmtMaxIdleTime,
-		}
-		db.dbs = append(db.dbs, ctx)
-		go ctx.checkIdleStmt()
+		db.dbs = append(db.dbs, newClient(d, n+i, db.hooks, db.stmtMaxIdleTime, db.stmtMaxSize))
+	}
+}
+
+// AddReplica adds read replicas of the database of index. see Client.AddReplica.
+func (db *DB) AddReplica(index int, dbs ...*sql.DB) {
+	db.mu.RLock()
+	c := db.dbs[index]
+	db.mu.RUnlock()
+
+	c.AddReplica(dbs...)
+}
+
+// SetReplicaPolicy sets the policy that routes queries to read replicas on all databases.
+func (db *DB) SetReplicaPolicy(p ReplicaPolicy) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, c := range db.dbs {
+		c.SetReplicaPolicy(p)
 	}
 }
 
+// SetStmtMaxIdleTime sets the maximum amount of time a prepared statement may be idle on all databases, including
+// the ones that are added later. It is ignored if d <= 0.
+func (db *DB) SetStmtMaxIdleTime(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.stmtMaxIdleTime = d
+	for _, c := range db.dbs {
+		c.SetStmtMaxIdleTime(d)
+	}
+}
+
+// SetStmtMaxSize sets the maximum number of cached prepared statements on all databases, including the ones that are
+// added later. If n <= 0, there is no limit.
+func (db *DB) SetStmtMaxSize(n int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.stmtMaxSize = n
+	for _, c := range db.dbs {
+		c.SetStmtMaxSize(n)
+	}
+}
+
+// AddHook registers hooks on all databases. They are inherited by every Client and Tx, including the ones of
+// databases that are added later.
+func (db *DB) AddHook(hooks ...Hook) {
+	db.hooks.add(hooks...)
+}
+
 // On selects the database context based on the shardid ID.
 func (db *DB) On(id shardid.ID) *Client {
 	db.mu.RLock()
@@ -0,0 +1,145 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+)
+
+// primary returns the current Client of database 0. It follows Replace and standby promotion, but the embedded
+// Client doesn't.
+func (db *DB) primary() *Client {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.dbs[0]
+}
+
+// Query executes a query on database 0. See Client.Query.
+func (db *DB) Query(query string, args ...any) (*Rows, error) {
+	return db.primary().Query(query, args...)
+}
+
+// QueryBuilder executes a query built by b on database 0. See Client.QueryBuilder.
+func (db *DB) QueryBuilder(ctx context.Context, b *Builder) (*Rows, error) {
+	return db.primary().QueryBuilder(ctx, b)
+}
+
+// QueryContext executes a query on database 0. See Client.QueryContext.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	return db.primary().QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that returns at most one row on database 0. See Client.QueryRow.
+func (db *DB) QueryRow(query string, args ...any) *Row {
+	return db.primary().QueryRow(query, args...)
+}
+
+// QueryRowBuilder executes a query built by b that returns at most one row on database 0. See
+// Client.QueryRowBuilder.
+func (db *DB) QueryRowBuilder(ctx context.Context, b *Builder) *Row {
+	return db.primary().QueryRowBuilder(ctx, b)
+}
+
+// QueryRowContext executes a query that returns at most one row on database 0. See Client.QueryRowContext.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return db.primary().QueryRowContext(ctx, query, args...)
+}
+
+// Exec executes a command on database 0. See Client.Exec.
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.primary().Exec(query, args...)
+}
+
+// ExecBuilder executes a command built by b on database 0. See Client.ExecBuilder.
+func (db *DB) ExecBuilder(ctx context.Context, b *Builder) (sql.Result, error) {
+	return db.primary().ExecBuilder(ctx, b)
+}
+
+// ExecContext executes a command on database 0. See Client.ExecContext.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.primary().ExecContext(ctx, query, args...)
+}
+
+// Begin starts a transaction on database 0. See Client.Begin.
+func (db *DB) Begin(opts *sql.TxOptions) (*Tx, error) {
+	return db.primary().Begin(opts)
+}
+
+// BeginTx starts a transaction on database 0. See Client.BeginTx.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	return db.primary().BeginTx(ctx, opts)
+}
+
+// Transaction runs fn in a transaction on database 0. See Client.Transaction.
+func (db *DB) Transaction(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	return db.primary().Transaction(ctx, opts, fn)
+}
+
+// Health returns the health state of database 0. See Client.Health.
+func (db *DB) Health() HealthState {
+	return db.primary().Health()
+}
+
+// StmtStats returns statistics of the prepared statement cache of database 0. See Client.StmtStats.
+func (db *DB) StmtStats() StmtStats {
+	return db.primary().StmtStats()
+}
+
+// Ping verifies the connection to database 0 is still alive. See sql.DB.Ping.
+func (db *DB) Ping() error {
+	return db.primary().Ping()
+}
+
+// PingContext verifies the connection to database 0 is still alive. See sql.DB.PingContext.
+func (db *DB) PingContext(ctx context.Context) error {
+	return db.primary().PingContext(ctx)
+}
+
+// Prepare creates a prepared statement on database 0. See sql.DB.Prepare.
+func (db *DB) Prepare(query string) (*sql.Stmt, error) {
+	return db.primary().Prepare(query)
+}
+
+// PrepareContext creates a prepared statement on database 0. See sql.DB.PrepareContext.
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.primary().PrepareContext(ctx, query)
+}
+
+// Conn returns a single connection of database 0. See sql.DB.Conn.
+func (db *DB) Conn(ctx context.Context) (*sql.Conn, error) {
+	return db.primary().Conn(ctx)
+}
+
+// Driver returns the driver of database 0. See sql.DB.Driver.
+func (db *DB) Driver() driver.Driver {
+	return db.primary().Driver()
+}
+
+// Stats returns statistics of database 0. See sql.DB.Stats.
+func (db *DB) Stats() sql.DBStats {
+	return db.primary().Stats()
+}
+
+// SetMaxOpenConns sets the maximum number of open connections to database 0. See sql.DB.SetMaxOpenConns.
+func (db *DB) SetMaxOpenConns(n int) {
+	db.primary().SetMaxOpenConns(n)
+}
+
+// SetMaxIdleConns sets the maximum number of idle connections of database 0. See sql.DB.SetMaxIdleConns.
+func (db *DB) SetMaxIdleConns(n int) {
+	db.primary().SetMaxIdleConns(n)
+}
+
+// SetConnMaxLifetime sets the maximum amount of time a connection of database 0 may be reused. See
+// sql.DB.SetConnMaxLifetime.
+func (db *DB) SetConnMaxLifetime(d time.Duration) {
+	db.primary().SetConnMaxLifetime(d)
+}
+
+// SetConnMaxIdleTime sets the maximum amount of time a connection of database 0 may be idle. See
+// sql.DB.SetConnMaxIdleTime.
+func (db *DB) SetConnMaxIdleTime(d time.Duration) {
+	db.primary().SetConnMaxIdleTime(d)
+}
@@ -0,0 +1,132 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrClientDrained = errors.New("sqle: client is drained")
+	ErrInvalidIndex  = errors.New("sqle: invalid database index")
+)
+
+// acquire marks a statement or transaction in flight, ErrClientDrained is returned if the client has been drained.
+func (db *Client) acquire() error {
+	atomic.AddInt64(&db.inflight, 1)
+	if atomic.LoadInt32(&db.drained) == 1 {
+		db.release()
+		return ErrClientDrained
+	}
+	return nil
+}
+
+// release marks a statement or transaction that is acquired finished.
+func (db *Client) release() {
+	atomic.AddInt64(&db.inflight, -1)
+}
+
+// wait rejects new statements, and waits for in-flight statements and transactions to finish.
+func (db *Client) wait(ctx context.Context) error {
+	atomic.StoreInt32(&db.drained, 1)
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&db.inflight) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// closeStmts stops checkIdleStmt and closes all cached statements.
+func (db *Client) closeStmts() {
+	db.stmtsMutex.Lock()
+	defer db.stmtsMutex.Unlock()
+
+	db.stopCheckIdleStmt()
+	for _, s := range db.stmts {
+		db.removeStmt(s)
+	}
+}
+
+// stopCheckIdleStmt stops checkIdleStmt if it is running. stmtsMutex should be locked.
+func (db *Client) stopCheckIdleStmt() {
+	if db.stop == nil {
+		return
+	}
+
+	select {
+	case <-db.stop:
+	default:
+		close(db.stop)
+	}
+}
+
+// Replace swaps the database of index with d, eg a shard is moved to new hardware. New statements are executed on d
+// immediately, and Replace returns once in-flight statements and transactions on the old database are finished.
+// Cached statements of the old database are closed even if ctx is done. Replicas are kept, and the old *sql.DB is
+// not closed.
+func (db *DB) Replace(ctx context.Context, index int, d *sql.DB) error {
+	db.mu.Lock()
+	if index < 0 || index >= len(db.dbs) {
+		db.mu.Unlock()
+		return ErrInvalidIndex
+	}
+
+	old := db.swap(index, d)
+	db.mu.Unlock()
+
+	err := old.wait(ctx)
+	old.closeStmts()
+
+	return err
+}
+
+// swap replaces the client of index with a new Client on d that inherits options and replicas of the old one, and
+// returns the old one. db.mu should be locked.
+func (db *DB) swap(index int, d *sql.DB) *Client {
+	old := db.dbs[index]
+
+	old.stmtsMutex.Lock()
+	stmtMaxIdleTime, stmtMaxSize := old.stmtMaxIdleTime, old.stmtMaxSize
+	old.stmtsMutex.Unlock()
+
+	c := newClient(d, index, db.hooks, stmtMaxIdleTime, stmtMaxSize)
+
+	old.replicasMu.RLock()
+	c.replicas, c.replicaPolicy = old.replicas, old.replicaPolicy
+	old.replicasMu.RUnlock()
+
+	db.dbs[index] = c
+
+	return old
+}
+
+// Drain takes the database of index out of service. New statements on it fail with ErrClientDrained, and Drain
+// returns once in-flight statements and transactions are finished. Cached statements of it and its replicas are
+// closed even if ctx is done, and the *sql.DB is not closed.
+func (db *DB) Drain(ctx context.Context, index int) error {
+	db.mu.RLock()
+	if index < 0 || index >= len(db.dbs) {
+		db.mu.RUnlock()
+		return ErrInvalidIndex
+	}
+	c := db.dbs[index]
+	db.mu.RUnlock()
+
+	err := c.wait(ctx)
+	c.closeStmts()
+	for _, r := range c.getReplicas() {
+		r.closeStmts()
+	}
+
+	return err
+}
@@ -0,0 +1,234 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d, err := sql.Open("sqlite3", filepath.Join(dir, name+".db"))
+		require.NoError(t, err)
+		_, err = d.Exec("CREATE TABLE nodes (name varchar(10))")
+		require.NoError(t, err)
+		_, err = d.Exec("INSERT INTO nodes (name) VALUES (?)", name)
+		require.NoError(t, err)
+		return d
+	}
+
+	getNode := func(t *testing.T, c *Client) string {
+		var name string
+		require.NoError(t, c.QueryRow("SELECT name FROM nodes WHERE name <> ?", "").Scan(&name))
+		return name
+	}
+
+	db := Open(createDB(t, "old"), createDB(t, "db1"))
+	db.AddReplica(0, createDB(t, "r1"))
+	db.SetStmtMaxSize(5)
+
+	old := db.Client
+	require.Equal(t, "r1", getNode(t, old))
+	var n int
+	require.NoError(t, old.QueryRowContext(WithPrimary(context.TODO()), "SELECT count(*) FROM nodes WHERE name <> ?", "").Scan(&n))
+	require.Equal(t, 1, old.StmtStats().Open)
+
+	require.ErrorIs(t, db.Replace(context.TODO(), 2, createDB(t, "db2")), ErrInvalidIndex)
+	require.ErrorIs(t, db.Drain(context.TODO(), -1), ErrInvalidIndex)
+
+	// in-flight transaction and rows on the old database
+	tx, err := old.BeginTx(context.TODO(), nil)
+	require.NoError(t, err)
+	rows, err := old.QueryContext(WithPrimary(context.TODO()), "SELECT name FROM nodes WHERE name = ?", "old")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, db.Replace(ctx, 0, createDB(t, "tmp")), context.DeadlineExceeded)
+
+	// new statements are executed on the new database immediately
+	c := db.dbs[0]
+	require.NotSame(t, old, c)
+	require.Equal(t, 0, c.Index)
+	require.Equal(t, 5, c.stmtMaxSize)
+	require.Same(t, old.getReplicas()[0], c.getReplicas()[0])
+
+	var name string
+	require.NoError(t, c.QueryRowContext(WithPrimary(context.TODO()), "SELECT name FROM nodes").Scan(&name))
+	require.Equal(t, "tmp", name)
+
+	// old database rejects new statements
+	_, err = old.Exec("DELETE FROM nodes")
+	require.ErrorIs(t, err, ErrClientDrained)
+
+	// cached statements are closed and checkIdleStmt is stopped even if ctx is done
+	require.Equal(t, 0, old.StmtStats().Open)
+	_, ok := <-old.stop
+	require.False(t, ok)
+	require.NoError(t, rows.Close())
+	require.NoError(t, tx.Rollback())
+
+	tx, err = c.BeginTx(context.TODO(), nil)
+	require.NoError(t, err)
+
+	d := createDB(t, "new")
+	done := make(chan error)
+	go func() {
+		done <- db.Replace(context.TODO(), 0, d)
+	}()
+
+	select {
+	case <-done:
+		require.Fail(t, "Replace should wait for in-flight transaction")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, tx.Commit())
+	require.NoError(t, <-done)
+
+	// replicas are kept
+	_, ok = <-c.stop
+	require.False(t, ok)
+	require.Equal(t, 1, db.dbs[0].getReplicas()[0].StmtStats().Open)
+	require.NoError(t, db.QueryRowContext(WithPrimary(context.TODO()), "SELECT name FROM nodes").Scan(&name))
+	require.Equal(t, "new", name)
+}
+
+func TestDrain(t *testing.T) {
+	d := createSQLite3()
+	d.SetMaxOpenConns(1)
+	_, err := d.Exec("CREATE TABLE nodes (name varchar(10))")
+	require.NoError(t, err)
+
+	r := createSQLite3()
+	db := Open(d)
+	db.AddReplica(0, r)
+
+	row := db.QueryRowContext(WithPrimary(context.TODO()), "SELECT count(*) FROM nodes WHERE name <> ?", "")
+
+	done := make(chan error)
+	go func() {
+		done <- db.Drain(context.TODO(), 0)
+	}()
+
+	select {
+	case <-done:
+		require.Fail(t, "Drain should wait for in-flight query")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var n int
+	require.NoError(t, row.Scan(&n))
+	require.NoError(t, <-done)
+
+	_, err = db.Query("SELECT * FROM nodes")
+	require.ErrorIs(t, err, ErrClientDrained)
+	require.ErrorIs(t, db.QueryRow("SELECT * FROM nodes").Err(), ErrClientDrained)
+	_, err = db.Exec("DELETE FROM nodes")
+	require.ErrorIs(t, err, ErrClientDrained)
+	_, err = db.Begin(nil)
+	require.ErrorIs(t, err, ErrClientDrained)
+
+	require.Equal(t, 0, db.StmtStats().Open)
+	_, ok := <-db.getReplicas()[0].stop
+	require.False(t, ok)
+}
+
+func TestReplaceConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d, err := sql.Open("sqlite3", filepath.Join(dir, name+".db"))
+		require.NoError(t, err)
+		_, err = d.Exec("CREATE TABLE nodes (name varchar(10))")
+		require.NoError(t, err)
+		return d
+	}
+
+	db := Open(createDB(t, "old"))
+	d := createDB(t, "new")
+
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			// statements on the old database are rejected once it is drained
+			if _, err := db.Exec("INSERT INTO nodes (name) VALUES (?)", "n"); err != nil && !errors.Is(err, ErrClientDrained) {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, db.Replace(context.TODO(), 0, d))
+	close(done)
+	require.NoError(t, <-errs)
+
+	_, err := db.Exec("INSERT INTO nodes (name) VALUES (?)", "n")
+	require.NoError(t, err)
+	require.NotSame(t, db.Client, db.dbs[0])
+}
+
+func TestReplaceSQLDBMethods(t *testing.T) {
+	dir := t.TempDir()
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d, err := sql.Open("sqlite3", filepath.Join(dir, name+".db"))
+		require.NoError(t, err)
+		_, err = d.Exec("CREATE TABLE nodes (name varchar(10))")
+		require.NoError(t, err)
+		_, err = d.Exec("INSERT INTO nodes (name) VALUES (?)", name)
+		require.NoError(t, err)
+		return d
+	}
+
+	old := createDB(t, "old")
+	d := createDB(t, "new")
+	db := Open(old)
+
+	require.NoError(t, db.Replace(context.TODO(), 0, d))
+	require.NoError(t, old.Close())
+
+	// *sql.DB methods on DB are called on the new database, even if the old one is closed
+	require.NoError(t, db.Ping())
+	require.NoError(t, db.PingContext(context.TODO()))
+
+	db.SetMaxOpenConns(3)
+	db.SetMaxIdleConns(2)
+	db.SetConnMaxLifetime(time.Minute)
+	db.SetConnMaxIdleTime(time.Minute)
+	require.Equal(t, 3, d.Stats().MaxOpenConnections)
+	require.Equal(t, d.Stats(), db.Stats())
+	require.Same(t, d.Driver(), db.Driver())
+
+	var name string
+	stmt, err := db.Prepare("SELECT name FROM nodes")
+	require.NoError(t, err)
+	require.NoError(t, stmt.QueryRow().Scan(&name))
+	require.Equal(t, "new", name)
+	require.NoError(t, stmt.Close())
+
+	stmt, err = db.PrepareContext(context.TODO(), "SELECT name FROM nodes")
+	require.NoError(t, err)
+	require.NoError(t, stmt.QueryRow().Scan(&name))
+	require.Equal(t, "new", name)
+	require.NoError(t, stmt.Close())
+
+	conn, err := db.Conn(context.TODO())
+	require.NoError(t, err)
+	require.NoError(t, conn.QueryRowContext(context.TODO(), "SELECT name FROM nodes").Scan(&name))
+	require.Equal(t, "new", name)
+	require.NoError(t, conn.Close())
+}
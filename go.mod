@@ -3,7 +3,9 @@ module github.com/yaitoo/sqle
 go 1.18
 
 require (
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/iancoleman/strcase v0.3.0
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/stretchr/testify v1.9.0
 	github.com/yaitoo/async v1.0.4
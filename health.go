@@ -0,0 +1,193 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthState is the health state of a database.
+type HealthState int32
+
+const (
+	Healthy   HealthState = iota // it is reachable, or it is not checked yet
+	Unhealthy                    // consecutive pings have failed for Threshold times
+)
+
+func (s HealthState) String() string {
+	if s == Unhealthy {
+		return "unhealthy"
+	}
+	return "healthy"
+}
+
+// HealthEvent is passed to HealthCheck.OnChange when the health state of a database is changed.
+type HealthEvent struct {
+	Index    int
+	Replica  int // 1-based index if it is a read replica, it is 0 on primary
+	State    HealthState
+	Err      error // the last ping error if it is unhealthy
+	Promoted bool  // the standby is promoted into Index as primary
+}
+
+// HealthCheck configures background health checks of all databases and their read replicas.
+type HealthCheck struct {
+	Interval  time.Duration // interval between checks, it is 10s if it is 0
+	Timeout   time.Duration // timeout of each ping, it is 3s if it is 0
+	Threshold int           // consecutive failures before a database is unhealthy, it is 3 if it is 0
+
+	// OnChange is called when a database becomes unhealthy, recovers or is replaced by its standby. it should not
+	// block.
+	OnChange func(e HealthEvent)
+}
+
+// Health returns the health state of the database. It is always Healthy if health check is not started.
+func (db *Client) Health() HealthState {
+	return HealthState(atomic.LoadInt32(&db.health))
+}
+
+// ping pings the database with timeout, and returns true if its health state is changed.
+func (db *Client) ping(ctx context.Context, hc HealthCheck) (HealthEvent, bool) {
+	ctx, cancel := context.WithTimeout(ctx, hc.Timeout)
+	defer cancel()
+
+	e := HealthEvent{Index: db.Index, Replica: db.replica}
+	e.Err = db.DB.PingContext(ctx)
+
+	if e.Err == nil {
+		atomic.StoreInt32(&db.failures, 0)
+		e.State = Healthy
+		return e, atomic.SwapInt32(&db.health, int32(Healthy)) != int32(Healthy)
+	}
+
+	if atomic.AddInt32(&db.failures, 1) < int32(hc.Threshold) {
+		return e, false
+	}
+
+	e.State = Unhealthy
+	return e, atomic.SwapInt32(&db.health, int32(Unhealthy)) != int32(Unhealthy)
+}
+
+// SetStandby sets the standby database of index. It is promoted into index as primary once the database of index is
+// unhealthy.
+func (db *DB) SetStandby(index int, d *sql.DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.standbys == nil {
+		db.standbys = make(map[int]*sql.DB)
+	}
+
+	db.standbys[index] = d
+}
+
+// StartHealthCheck starts to check health of all databases and their read replicas in background. Unhealthy
+// replicas are skipped by queries, and unhealthy databases are replaced by their standbys. It restarts with hc if it
+// has been started.
+func (db *DB) StartHealthCheck(hc HealthCheck) {
+	if hc.Interval <= 0 {
+		hc.Interval = 10 * time.Second
+	}
+
+	if hc.Timeout <= 0 {
+		hc.Timeout = 3 * time.Second
+	}
+
+	if hc.Threshold <= 0 {
+		hc.Threshold = 3
+	}
+
+	db.StopHealthCheck()
+
+	stop := make(chan struct{})
+
+	db.mu.Lock()
+	db.healthStop = stop
+	db.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(hc.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				db.checkHealth(context.Background(), hc)
+			}
+		}
+	}()
+}
+
+// StopHealthCheck stops health check that is started by StartHealthCheck.
+func (db *DB) StopHealthCheck() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.healthStop != nil {
+		close(db.healthStop)
+		db.healthStop = nil
+	}
+}
+
+// checkHealth pings all databases and their replicas concurrently, and promotes standbys of unhealthy databases.
+func (db *DB) checkHealth(ctx context.Context, hc HealthCheck) {
+	db.mu.RLock()
+	clients := make([]*Client, 0, len(db.dbs))
+	for _, c := range db.dbs {
+		clients = append(clients, c)
+		clients = append(clients, c.getReplicas()...)
+	}
+	db.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+
+			e, changed := c.ping(ctx, hc)
+			if !changed {
+				return
+			}
+
+			if hc.OnChange != nil {
+				hc.OnChange(e)
+			}
+
+			if e.State == Unhealthy && e.Replica == 0 && db.promote(c) {
+				if hc.OnChange != nil {
+					hc.OnChange(HealthEvent{Index: e.Index, State: Healthy, Promoted: true})
+				}
+			}
+		}(c)
+	}
+
+	wg.Wait()
+}
+
+// promote replaces c with a new Client on its standby, and returns false if there is no standby. c is drained in
+// background, and it is closed by DB.Close.
+func (db *DB) promote(c *Client) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	standby, ok := db.standbys[c.Index]
+	if !ok || db.dbs[c.Index] != c {
+		return false
+	}
+
+	delete(db.standbys, c.Index)
+
+	old := db.swap(c.Index, standby)
+	db.retired = append(db.retired, old)
+	go func() {
+		old.wait(context.Background()) //nolint: errcheck
+		old.closeStmts()
+	}()
+
+	return true
+}
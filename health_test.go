@@ -0,0 +1,152 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle/shardid"
+)
+
+func TestHealthCheck(t *testing.T) {
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d := createSQLite3()
+		d.SetMaxOpenConns(1)
+		_, err := d.Exec("CREATE TABLE nodes (name varchar(10))")
+		require.NoError(t, err)
+		_, err = d.Exec("INSERT INTO nodes (name) VALUES (?)", name)
+		require.NoError(t, err)
+		return d
+	}
+
+	getNode := func(t *testing.T, c *Client) string {
+		var name string
+		require.NoError(t, c.QueryRow("SELECT name FROM nodes").Scan(&name))
+		return name
+	}
+
+	t.Run("standby_should_be_promoted", func(t *testing.T) {
+		d0 := createDB(t, "primary")
+		db := Open(d0, createDB(t, "db1"))
+		db.SetStandby(0, createDB(t, "standby"))
+		db.AddReplica(0, createDB(t, "r1"))
+		db.SetStmtMaxSize(5)
+
+		var mu sync.Mutex
+		var events []HealthEvent
+		hc := HealthCheck{
+			Timeout:   time.Second,
+			Threshold: 2,
+			OnChange: func(e HealthEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, e)
+			},
+		}
+
+		db.checkHealth(context.TODO(), hc)
+		require.Empty(t, events)
+		require.Equal(t, Healthy, db.Health())
+
+		require.NoError(t, d0.Close())
+
+		// it is unhealthy after consecutive failures
+		db.checkHealth(context.TODO(), hc)
+		require.Empty(t, events)
+
+		c := db.Client
+		db.checkHealth(context.TODO(), hc)
+		require.Len(t, events, 2)
+		require.Equal(t, 0, events[0].Index)
+		require.Equal(t, Unhealthy, events[0].State)
+		require.Error(t, events[0].Err)
+		require.Equal(t, Unhealthy, c.Health())
+		require.Equal(t, HealthEvent{Index: 0, State: Healthy, Promoted: true}, events[1])
+
+		// standby is promoted with replicas and options of the unhealthy database
+		id := shardid.Build(time.Now().UnixMilli(), 0, 0, shardid.NoRotate, 0)
+		p := db.On(id)
+		require.NotSame(t, c, p)
+		require.Same(t, p, db.dbs[0])
+		require.Equal(t, 0, p.Index)
+		require.Equal(t, 5, p.stmtMaxSize)
+		require.Same(t, c.getReplicas()[0], p.getReplicas()[0])
+		require.Equal(t, "r1", getNode(t, p))
+
+		var name string
+		require.NoError(t, p.QueryRowContext(WithPrimary(context.TODO()), "SELECT name FROM nodes").Scan(&name))
+		require.Equal(t, "standby", name)
+
+		// standby is promoted only once
+		events = nil
+		db.checkHealth(context.TODO(), hc)
+		require.Empty(t, events)
+	})
+
+	t.Run("unhealthy_replica_should_be_skipped", func(t *testing.T) {
+		r1 := createDB(t, "r1")
+		db := Open(createDB(t, "primary"))
+		db.AddReplica(0, r1, createDB(t, "r2"))
+
+		var events []HealthEvent
+		hc := HealthCheck{
+			Timeout:   time.Second,
+			Threshold: 1,
+			OnChange: func(e HealthEvent) {
+				events = append(events, e)
+			},
+		}
+
+		require.NoError(t, r1.Close())
+		db.checkHealth(context.TODO(), hc)
+		require.Len(t, events, 1)
+		require.Equal(t, 1, events[0].Replica)
+		require.Equal(t, Unhealthy, events[0].State)
+
+		for i := 0; i < 3; i++ {
+			require.Equal(t, "r2", getNode(t, db.Client))
+		}
+
+		// primary is used if all replicas are unhealthy
+		r2 := db.Client.getReplicas()[1]
+		atomic.StoreInt32(&r2.health, int32(Unhealthy))
+		require.Equal(t, "primary", getNode(t, db.Client))
+
+		// recovered
+		events = nil
+		db.checkHealth(context.TODO(), hc)
+		require.Equal(t, []HealthEvent{{Index: 0, Replica: 2, State: Healthy}}, events)
+		require.Equal(t, "r2", getNode(t, db.Client))
+	})
+
+	t.Run("health_check_should_run_in_background", func(t *testing.T) {
+		d0 := createDB(t, "primary")
+		db := Open(d0)
+
+		ch := make(chan HealthEvent, 10)
+		db.StartHealthCheck(HealthCheck{
+			Interval:  10 * time.Millisecond,
+			Threshold: 1,
+			OnChange: func(e HealthEvent) {
+				ch <- e
+			},
+		})
+		defer db.StopHealthCheck()
+
+		require.NoError(t, d0.Close())
+
+		select {
+		case e := <-ch:
+			require.Equal(t, Unhealthy, e.State)
+		case <-time.After(time.Second):
+			require.Fail(t, "unhealthy event is not received")
+		}
+
+		db.StopHealthCheck()
+		db.StopHealthCheck()
+	})
+}
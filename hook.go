@@ -0,0 +1,155 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Hook observes queries, commands and transactions that are executed by Client and Tx. It can be used for
+// slow-query logging, metrics and tracing without wrapping the driver.
+type Hook interface {
+	// Before is called before a query is executed or a transaction is started. The returned context is passed to the
+	// driver and After.
+	Before(ctx context.Context, e *HookEvent) context.Context
+
+	// After is called after a query is executed or a transaction is finished with Duration, RowsAffected and Err
+	// populated.
+	After(ctx context.Context, e *HookEvent)
+}
+
+// HookOp is the operation of a HookEvent.
+type HookOp int
+
+const (
+	OpQuery HookOp = iota // Query, QueryRow and their variants
+	OpExec                // Exec and its variants
+	OpTx                  // a transaction from BeginTx to Commit or Rollback
+)
+
+func (op HookOp) String() string {
+	switch op {
+	case OpExec:
+		return "exec"
+	case OpTx:
+		return "tx"
+	default:
+		return "query"
+	}
+}
+
+// HookEvent describes a query, command or transaction that is executed by Client or Tx.
+type HookEvent struct {
+	Op      HookOp
+	Index   int    // index of database that the query is executed on
+	Replica int    // 1-based index of the read replica that the query is routed to, it is 0 on primary
+	Query   string // it is BEGIN for OpTx in Before, and COMMIT or ROLLBACK in After
+	Args    []any
+	InTx    bool // it is executed in a transaction
+
+	Rotate string // rotated table name that is set by Builder.On
+	DHT    string // DHT name that is set by WithDHT
+
+	Prepared   bool // it is executed by a prepared statement
+	StmtCached bool // the prepared statement is reused from cache
+
+	Start        time.Time
+	Duration     time.Duration
+	RowsAffected int64 // it is only populated for OpExec if the driver supports it
+	Err          error
+
+	hooks []Hook
+}
+
+type hookContextKey int
+
+const (
+	hookEventKey hookContextKey = iota
+	hookRotateKey
+	hookDHTKey
+)
+
+// WithDHT returns a copy of ctx that tags queries with the DHT name for hooks, eg a client that is selected by OnDHT.
+func WithDHT(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, hookDHTKey, name)
+}
+
+// withRotate returns a copy of ctx that tags queries with the rotated table name of b for hooks.
+func withRotate(ctx context.Context, b *Builder) context.Context {
+	rotate, ok := b.inputs["rotate"]
+	if !ok || rotate == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, hookRotateKey, rotate)
+}
+
+// setStmtCached marks the event of ctx as executed by a prepared statement.
+func setStmtCached(ctx context.Context, cached bool) {
+	e, ok := ctx.Value(hookEventKey).(*HookEvent)
+	if ok {
+		e.Prepared = true
+		e.StmtCached = cached
+	}
+}
+
+// hooks is shared by DB, its clients and their transactions, so a hook that is added later applies to all of them.
+type hooks struct {
+	mu    sync.RWMutex
+	items []Hook
+}
+
+func (h *hooks) add(items ...Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.items = append(h.items, items...)
+}
+
+// before calls Before of all hooks in order with e that Op, Index, Replica, Query, Args and InTx are populated. nil
+// event is returned if there is no any hook.
+func (h *hooks) before(ctx context.Context, e HookEvent) (context.Context, *HookEvent) {
+	if h == nil {
+		return ctx, nil
+	}
+
+	h.mu.RLock()
+	items := h.items
+	h.mu.RUnlock()
+
+	if len(items) == 0 {
+		return ctx, nil
+	}
+
+	e.Start = time.Now()
+	e.hooks = items
+	e.Rotate, _ = ctx.Value(hookRotateKey).(string)
+	e.DHT, _ = ctx.Value(hookDHTKey).(string)
+
+	for _, it := range items {
+		ctx = it.Before(ctx, &e)
+	}
+
+	return context.WithValue(ctx, hookEventKey, &e), &e
+}
+
+// after calls After of all hooks in reverse order, so the first hook wraps the others like a middleware.
+func (h *hooks) after(ctx context.Context, e *HookEvent, result sql.Result, err error) {
+	if e == nil {
+		return
+	}
+
+	e.Duration = time.Since(e.Start)
+	e.Err = err
+	if result != nil && err == nil {
+		n, err := result.RowsAffected()
+		if err == nil {
+			e.RowsAffected = n
+		}
+	}
+
+	for i := len(e.hooks) - 1; i >= 0; i-- {
+		e.hooks[i].After(ctx, e)
+	}
+}
@@ -0,0 +1,147 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle/shardid"
+)
+
+type hookKey struct{}
+
+type recordHook struct {
+	name   string
+	calls  *[]string
+	events []HookEvent
+}
+
+func (h *recordHook) Before(ctx context.Context, e *HookEvent) context.Context {
+	*h.calls = append(*h.calls, "before:"+h.name)
+	return context.WithValue(ctx, hookKey{}, h.name)
+}
+
+func (h *recordHook) After(ctx context.Context, e *HookEvent) {
+	*h.calls = append(*h.calls, "after:"+h.name+":"+ctx.Value(hookKey{}).(string))
+	h.events = append(h.events, *e)
+}
+
+func TestHook(t *testing.T) {
+	d0 := createSQLite3()
+	d0.SetMaxOpenConns(1)
+	d1 := createSQLite3()
+	d1.SetMaxOpenConns(1)
+
+	db := Open(d0)
+
+	var calls []string
+	h := &recordHook{name: "a", calls: &calls}
+	db.AddHook(h)
+
+	_, err := db.Exec("CREATE TABLE users (id int, status int, PRIMARY KEY (id))")
+	require.NoError(t, err)
+
+	result, err := db.ExecContext(context.TODO(), "INSERT INTO users (id, status) VALUES (?, ?), (?, ?)", 1, 1, 2, 1)
+	require.NoError(t, err)
+	n, err := result.RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	require.Len(t, h.events, 2)
+	e := h.events[1]
+	require.Equal(t, 0, e.Index)
+	require.Equal(t, "INSERT INTO users (id, status) VALUES (?, ?), (?, ?)", e.Query)
+	require.Equal(t, []any{1, 1, 2, 1}, e.Args)
+	require.Equal(t, OpExec, e.Op)
+	require.False(t, e.InTx)
+	require.True(t, e.Prepared)
+	require.False(t, e.StmtCached)
+	require.Equal(t, int64(2), e.RowsAffected)
+	require.Greater(t, e.Duration.Nanoseconds(), int64(0))
+	require.NoError(t, e.Err)
+
+	var id int
+	require.NoError(t, db.QueryRow("SELECT id FROM users WHERE id = ?", 2).Scan(&id))
+	require.Equal(t, 2, id)
+
+	e = h.events[2]
+	require.Equal(t, OpQuery, e.Op)
+	require.Equal(t, "SELECT id FROM users WHERE id = ?", e.Query)
+
+	require.NoError(t, db.QueryRowContext(WithDHT(context.TODO(), "users"), "SELECT id FROM users WHERE id = ?", 1).Scan(&id))
+	e = h.events[3]
+	require.True(t, e.StmtCached)
+	require.Equal(t, "users", e.DHT)
+	h.events = h.events[:3]
+
+	rows, err := db.Query("SELECT id FROM users_not_found")
+	require.Error(t, err)
+	require.Nil(t, rows)
+	require.Equal(t, err, h.events[3].Err)
+
+	// hooks run like a middleware, and context returned by Before is passed to After
+	calls = nil
+	db.AddHook(&recordHook{name: "b", calls: &calls})
+	_, err = db.Exec("UPDATE users SET status = 2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"before:a", "before:b", "after:b:b", "after:a:b"}, calls)
+
+	// tx inherits hooks of its client
+	h.events = nil
+	err = db.Transaction(context.TODO(), nil, func(ctx context.Context, tx *Tx) error {
+		rows, err := tx.QueryContext(ctx, "SELECT id FROM users WHERE status = ?", 2)
+		if err != nil {
+			return err
+		}
+		rows.Close()
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", 1)
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, h.events, 3)
+	require.True(t, h.events[0].InTx)
+	require.Equal(t, OpQuery, h.events[0].Op)
+	require.True(t, h.events[1].InTx)
+	require.Equal(t, int64(1), h.events[1].RowsAffected)
+	require.Equal(t, OpTx, h.events[2].Op)
+	require.Equal(t, "COMMIT", h.events[2].Query)
+	require.GreaterOrEqual(t, h.events[2].Duration, h.events[0].Duration+h.events[1].Duration)
+
+	h.events = nil
+	tx, err := db.BeginTx(context.TODO(), nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	require.Error(t, tx.Rollback())
+	require.Len(t, h.events, 1)
+	require.Equal(t, "ROLLBACK", h.events[0].Query)
+
+	// rotated table name is set by Builder.On
+	h.events = nil
+	id0 := shardid.Build(time.Now().UnixMilli(), 0, 0, shardid.MonthlyRotate, 0)
+	_, err = db.ExecBuilder(context.TODO(), New().On(id0).SQL("CREATE TABLE logs<rotate> (id int)"))
+	require.NoError(t, err)
+	require.Equal(t, id0.RotateName(), h.events[0].Rotate)
+
+	// databases that are added later inherit hooks too
+	h.events = nil
+	db.Add(d1)
+	_, err = db.On(shardid.Build(time.Now().UnixMilli(), 0, 1, shardid.NoRotate, 0)).Exec("CREATE TABLE users (id int)")
+	require.NoError(t, err)
+	require.Len(t, h.events, 1)
+	require.Equal(t, 1, h.events[0].Index)
+
+	// clients without hooks work as before
+	c := &Client{DB: d0, stmts: make(map[string]*Stmt)}
+	var count int
+	require.NoError(t, c.QueryRow("SELECT count(*) FROM users").Scan(&count))
+	require.Equal(t, 1, count)
+
+	tx, err = c.BeginTx(context.TODO(), &sql.TxOptions{})
+	require.NoError(t, err)
+	_, err = tx.Exec("DELETE FROM users")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+}
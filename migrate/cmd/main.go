@@ -1,5 +1,350 @@
+// Command sqle-migrate migrates, rotates, prunes and verifies sharding databases with sql files organized in filesystem.
+//
+//	sqle-migrate up|down|status|rotate|prune|verify|repair|baseline|snapshot|drift --dsn ... [--dsn ...] --dir ./db --module name
 package main
 
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/yaitoo/sqle"
+	"github.com/yaitoo/sqle/migrate"
+)
+
+var errUsage = errors.New("usage: sqle-migrate up|down|status|rotate|prune|verify|repair|baseline|snapshot|drift --dsn <dsn> [--dsn <dsn>] --dir <dir> [--module <name>] [--driver <driver>] [--suffix <suffix>] [--to <version>] [--all] [--dry-run] [--lock-timeout <duration>] [--concurrency <n>] [--continue-on-error] [--look-ahead <n>] [--backfill <n>] [--on-modified warn|fail|rerun] [--input <name>=<value>] [--schema <file>]")
+
+var errDrift = errors.New("schema drifted")
+
+// dsnList collects repeated --dsn flags, one per sharding database.
+type dsnList []string
+
+func (l *dsnList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *dsnList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// inputMap collects repeated --input name=value flags.
+type inputMap map[string]string
+
+func (m inputMap) String() string {
+	items := make([]string, 0, len(m))
+	for k, v := range m {
+		items = append(items, k+"="+v)
+	}
+	return strings.Join(items, ",")
+}
+
+func (m inputMap) Set(v string) error {
+	name, value, ok := strings.Cut(v, "=")
+	if !ok || name == "" {
+		return errors.New("input should be name=value")
+	}
+	m[name] = value
+	return nil
+}
+
 func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sqle-migrate:", err)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, output io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	cmd := args[0]
+	switch cmd {
+	case "up", "down", "status", "rotate", "prune", "verify", "repair", "baseline", "snapshot", "drift":
+	default:
+		return errUsage
+	}
+
+	var (
+		dsns   dsnList
+		driver string
+		dir    string
+		module string
+		suffix string
+		to     string
+		all    bool
+		dryRun bool
+
+		lockTimeout     time.Duration
+		concurrency     int
+		continueOnError bool
+
+		lookAhead int
+		backfill  int
+
+		onModified string
+		inputs     = make(inputMap)
+
+		schema string
+	)
+
+	fs := flag.NewFlagSet("sqle-migrate "+cmd, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Var(&dsns, "dsn", "data source name of a database, repeat it for each sharding database")
+	fs.StringVar(&driver, "driver", "mysql", "database driver name (mysql/postgres/sqlite3)")
+	fs.StringVar(&dir, "dir", ".", "directory that contains version and rotation directories")
+	fs.StringVar(&module, "module", "", "module name of migrations")
+	fs.StringVar(&suffix, "suffix", ".sql", "suffix of migration script files")
+	fs.StringVar(&to, "to", "", "version to roll back to with down or to baseline, it is required by down without --all and by baseline")
+	fs.BoolVar(&all, "all", false, "roll back all versions with down")
+	fs.DurationVar(&lockTimeout, "lock-timeout", migrate.DefaultLockTimeout, "duration to wait for the migration lock on each database")
+	fs.IntVar(&concurrency, "concurrency", 1, "maximum number of databases that are migrated concurrently by up")
+	fs.BoolVar(&continueOnError, "continue-on-error", false, "keep migrating other databases when a database fails")
+	fs.IntVar(&lookAhead, "look-ahead", 1, "number of periods after current period that rotate creates rotated tables for")
+	fs.IntVar(&backfill, "backfill", 0, "number of periods before current period that rotate creates rotated tables for")
+	fs.StringVar(&onModified, "on-modified", "warn", "how up handles a script that has been modified after it was executed (warn/fail/rerun)")
+	fs.Var(inputs, "input", "input that replaces <name> in scripts, repeat it for each input. eg --input engine=InnoDB")
+	fs.StringVar(&schema, "schema", "", "snapshot file that drift compares databases with, databases are compared with the first one if it is empty")
+	fs.BoolVar(&dryRun, "dry-run", false, "print statements that up/rotate would execute without executing them")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+
+	if len(dsns) == 0 {
+		return errUsage
+	}
+
+	if dryRun && cmd != "up" && cmd != "rotate" {
+		return errUsage
+	}
+
+	if cmd == "baseline" && to == "" {
+		return errUsage
+	}
+
+	// all versions are rolled back only if it is explicit
+	if cmd == "down" && to == "" && !all {
+		return errUsage
+	}
+
+	if all && (cmd != "down" || to != "") {
+		return errUsage
+	}
+
+	// a snapshot is the schema of one database, shards are compared with it or with each other by drift
+	if cmd == "snapshot" && len(dsns) > 1 {
+		return errUsage
+	}
+
+	policy, ok := getModifiedPolicy(onModified)
+	if !ok {
+		return errUsage
+	}
+
+	dbs := make([]*sqle.DB, 0, len(dsns))
+	for _, dsn := range dsns {
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		dbs = append(dbs, sqle.Open(db))
+	}
+
+	options := []migrate.Option{
+		migrate.WithModule(module),
+		migrate.WithSuffix(suffix),
+		migrate.WithDialect(getDialect(driver)),
+		migrate.WithLockTimeout(lockTimeout),
+		migrate.WithConcurrency(concurrency),
+		migrate.WithLookAhead(lookAhead),
+		migrate.WithBackfill(backfill),
+		migrate.WithModifiedPolicy(policy),
+		migrate.WithInputs(inputs),
+	}
+
+	if continueOnError {
+		options = append(options, migrate.WithContinueOnError())
+	}
+
+	m := migrate.New(dbs...)
+	err := m.Discover(os.DirFS(dir), options...)
+	if err != nil {
+		return err
+	}
+
+	// read-only commands should not create or alter bookkeeping tables
+	if !dryRun && !isReadOnly(cmd) {
+		if err = m.Init(ctx); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		var steps []migrate.Step
+		if cmd == "up" {
+			steps, err = m.Plan(ctx)
+		} else {
+			steps, err = m.PlanRotate(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		printSteps(output, steps)
+		return nil
+	}
+
+	switch cmd {
+	case "up":
+		reports, err := m.MigrateWithReport(ctx)
+		printReports(output, reports)
+		if err != nil {
+			return err
+		}
+		return m.Verify(ctx)
+	case "down":
+		return m.Rollback(ctx, to)
+	case "status":
+		return m.Status(ctx)
+	case "rotate":
+		return m.Rotate(ctx)
+	case "prune":
+		return m.Prune(ctx)
+	case "repair":
+		return m.Repair(ctx)
+	case "baseline":
+		return m.Baseline(ctx, to)
+	case "snapshot":
+		items, err := m.Snapshot(ctx)
+		if err != nil {
+			return err
+		}
+		return migrate.WriteSchema(output, items[0])
+	case "drift":
+		return printDrifts(ctx, output, m, schema)
+	default: // verify
+		return m.Verify(ctx)
+	}
+}
+
+// printSteps prints planned steps and their statements.
+func printSteps(w io.Writer, steps []migrate.Step) {
+	for _, s := range steps {
+		db := fmt.Sprintf("db-%v", s.DB)
+		if s.Tenant != "" {
+			db += " tenant-" + s.Tenant
+		}
+
+		if s.Version == "" {
+			fmt.Fprintf(w, "%s %s [%s]\n", db, s.Name, s.Status)
+		} else {
+			fmt.Fprintf(w, "%s v%s %d_%s [%s]\n", db, s.Version, s.Rank, s.Name, s.Status)
+		}
+
+		for _, it := range s.Statements {
+			if it.Rotate == "" {
+				fmt.Fprintf(w, "    %s\n", it.SQL)
+			} else {
+				fmt.Fprintf(w, "    (%s) %s\n", it.Rotate, it.SQL)
+			}
+		}
+	}
+}
+
+// isReadOnly checks if cmd only reads databases.
+func isReadOnly(cmd string) bool {
+	switch cmd {
+	case "status", "verify", "snapshot", "drift":
+		return true
+	default:
+		return false
+	}
+}
+
+// getDialect returns the dialect of bookkeeping tables for driver.
+func getDialect(driver string) migrate.Dialect {
+	switch driver {
+	case "postgres", "pgx":
+		return migrate.Postgres
+	case "sqlite3", "sqlite":
+		return migrate.SQLite
+	case "oracle", "godror":
+		return migrate.Oracle
+	default:
+		return migrate.MySQL
+	}
+}
+
+// getModifiedPolicy returns the policy of modified scripts by name.
+func getModifiedPolicy(name string) (migrate.ModifiedPolicy, bool) {
+	switch name {
+	case "warn":
+		return migrate.ModifiedWarn, true
+	case "fail":
+		return migrate.ModifiedFail, true
+	case "rerun":
+		return migrate.ModifiedRerun, true
+	default:
+		return migrate.ModifiedWarn, false
+	}
+}
+
+// printReports prints the summary of migrated databases.
+func printReports(w io.Writer, reports []migrate.Report) {
+	for _, r := range reports {
+		fmt.Fprintf(w, "db-%v: applied %d, skipped %d, failed %d in %s\n", r.DB, len(r.Applied), len(r.Skipped), len(r.Failed), r.Duration)
+		for _, it := range r.Failed {
+			fmt.Fprintf(w, "    v%s %d_%s: %s\n", it.Version, it.Rank, it.Name, it.Err)
+		}
+	}
+}
+
+// printDrifts prints drifts between databases and the snapshot file, errDrift is returned if any drift is found.
+func printDrifts(ctx context.Context, w io.Writer, m *migrate.Migrator, file string) error {
+	var expected *migrate.Schema
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		s, err := migrate.ReadSchema(f)
+		if err != nil {
+			return err
+		}
+		expected = &s
+	}
+
+	drifts, err := m.Drift(ctx, expected)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range drifts {
+		fmt.Fprintln(w, d)
+	}
+
+	if len(drifts) > 0 {
+		return fmt.Errorf("%w: %d changes", errDrift, len(drifts))
+	}
 
+	return nil
 }
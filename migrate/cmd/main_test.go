@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle/migrate"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "0.1.0"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "monthly"), 0o755))
+
+	script := filepath.Join(dir, "0.1.0", "1_create_table_roles.sql")
+	require.NoError(t, os.WriteFile(script, []byte("CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "monthly", "logs.sql"), []byte("CREATE TABLE IF NOT EXISTS logs<rotate> (id int NOT NULL, PRIMARY KEY (id));"), 0o600))
+
+	db0 := filepath.Join(dir, "db0.db")
+	db1 := filepath.Join(dir, "db1.db")
+
+	args := func(cmd string) []string {
+		return []string{cmd, "--driver", "sqlite3", "--dsn", db0, "--dsn", db1, "--dir", dir, "--module", "tests"}
+	}
+
+	ctx := context.TODO()
+
+	require.ErrorIs(t, run(ctx, nil, io.Discard), errUsage)
+	require.ErrorIs(t, run(ctx, []string{"down"}, io.Discard), errUsage)
+	require.ErrorIs(t, run(ctx, []string{"up", "--dir", dir}, io.Discard), errUsage)
+
+	require.NoError(t, run(ctx, args("status"), io.Discard))
+	require.ErrorIs(t, run(ctx, append(args("status"), "--dry-run"), io.Discard), errUsage)
+
+	var buf bytes.Buffer
+	require.NoError(t, run(ctx, append(args("up"), "--dry-run"), &buf))
+	require.Contains(t, buf.String(), "db-1 v0.1.0 1_create_table_roles [new]")
+	require.ErrorIs(t, run(ctx, append(args("up"), "--input", "engine"), io.Discard), errUsage)
+	require.Contains(t, buf.String(), "    CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));")
+
+	buf.Reset()
+	require.NoError(t, run(ctx, append(args("rotate"), "--dry-run"), &buf))
+	require.Contains(t, buf.String(), "db-0 logs [new]")
+	require.NoError(t, run(ctx, args("verify"), io.Discard))
+
+	// read-only commands should not create bookkeeping tables
+	for _, dsn := range []string{db0, db1} {
+		d, err := sql.Open("sqlite3", dsn)
+		require.NoError(t, err)
+		var n int
+		require.NoError(t, d.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name LIKE 'sqle_%'").Scan(&n))
+		require.Equal(t, 0, n)
+		require.NoError(t, d.Close())
+	}
+
+	buf.Reset()
+	require.NoError(t, run(ctx, append(args("up"), "--concurrency", "2"), &buf))
+	require.Contains(t, buf.String(), "db-0: applied 1, skipped 0, failed 0")
+	require.Contains(t, buf.String(), "db-1: applied 1, skipped 0, failed 0")
+	require.NoError(t, run(ctx, append(args("rotate"), "--look-ahead", "2"), io.Discard))
+	require.NoError(t, run(ctx, args("prune"), io.Discard))
+	require.NoError(t, run(ctx, args("verify"), io.Discard))
+	require.ErrorIs(t, run(ctx, args("down"), io.Discard), errUsage)
+	require.ErrorIs(t, run(ctx, append(args("down"), "--all", "--to", "0.0.1"), io.Discard), errUsage)
+	require.ErrorIs(t, run(ctx, append(args("up"), "--all"), io.Discard), errUsage)
+	require.ErrorIs(t, run(ctx, append(args("down"), "--all"), io.Discard), migrate.ErrMissingDownScript)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0.1.0", "1_create_table_roles.down.sql"), []byte("DROP TABLE IF EXISTS roles;"), 0o600))
+	require.NoError(t, run(ctx, append(args("down"), "--to", "0.0.1"), io.Discard))
+	require.NoError(t, run(ctx, args("up"), io.Discard))
+
+	require.NoError(t, os.WriteFile(script, []byte("CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, name varchar(45), PRIMARY KEY (id));"), 0o600))
+
+	require.ErrorIs(t, run(ctx, args("verify"), io.Discard), migrate.ErrMigrationModified)
+	require.ErrorIs(t, run(ctx, args("status"), io.Discard), migrate.ErrMigrationModified)
+	require.ErrorIs(t, run(ctx, args("up"), io.Discard), migrate.ErrMigrationModified)
+	require.ErrorIs(t, run(ctx, append(args("up"), "--on-modified", "fail"), io.Discard), migrate.ErrMigrationModified)
+	require.ErrorIs(t, run(ctx, append(args("up"), "--on-modified", "skip"), io.Discard), errUsage)
+
+	require.NoError(t, run(ctx, args("repair"), io.Discard))
+	require.NoError(t, run(ctx, args("verify"), io.Discard))
+
+	require.ErrorIs(t, run(ctx, args("baseline"), io.Discard), errUsage)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "0.2.0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0.2.0", "1_create_table_users.sql"), []byte("CREATE TABLE users (id int NOT NULL, PRIMARY KEY (id));"), 0o600))
+	require.NoError(t, run(ctx, append(args("baseline"), "--to", "0.2.0"), io.Discard))
+	require.NoError(t, run(ctx, args("up"), io.Discard))
+
+	buf.Reset()
+	require.ErrorIs(t, run(ctx, args("snapshot"), io.Discard), errUsage)
+	require.NoError(t, run(ctx, []string{"snapshot", "--driver", "sqlite3", "--dsn", db0, "--dir", dir, "--module", "tests"}, &buf))
+	require.Contains(t, buf.String(), `"name": "roles"`)
+	snapshot := filepath.Join(dir, "schema.json")
+	require.NoError(t, os.WriteFile(snapshot, buf.Bytes(), 0o600))
+	require.NoError(t, run(ctx, args("drift"), io.Discard))
+	require.NoError(t, run(ctx, append(args("drift"), "--schema", snapshot), io.Discard))
+
+	db, err := sql.Open("sqlite3", db1)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("ALTER TABLE roles ADD COLUMN code varchar(10)")
+	require.NoError(t, err)
+
+	buf.Reset()
+	require.ErrorIs(t, run(ctx, args("drift"), &buf), errDrift)
+	require.Equal(t, "db-1 roles.code unexpected\n", buf.String())
+	require.ErrorIs(t, run(ctx, append(args("drift"), "--schema", snapshot), io.Discard), errDrift)
+}
@@ -0,0 +1,210 @@
+package migrate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yaitoo/sqle"
+)
+
+// Dialect generates bookkeeping DDL, placeholders and column types for a database engine.
+type Dialect struct {
+	Name string
+
+	// Use applies quote and parameterize options on bookkeeping statements. eg sqle.UsePostgres
+	Use func(b *sqle.Builder)
+
+	Varchar  string // varchar type name, it is followed by size
+	Int      string
+	Datetime string
+	Text     string
+
+	// Empty is stored for empty strings, eg module and tenant. It is empty except on databases that treat '' as NULL.
+	Empty string
+}
+
+var (
+	// MySQL is the dialect of MySQL and MariaDB
+	MySQL = Dialect{
+		Name:     "mysql",
+		Use:      sqle.UseMySQL,
+		Varchar:  "varchar",
+		Int:      "int",
+		Datetime: "datetime",
+		Text:     "text",
+	}
+
+	// SQLite is the dialect of SQLite
+	SQLite = Dialect{
+		Name:     "sqlite",
+		Use:      sqle.UseMySQL,
+		Varchar:  "varchar",
+		Int:      "int",
+		Datetime: "datetime",
+		Text:     "text",
+	}
+
+	// Postgres is the dialect of PostgreSQL
+	Postgres = Dialect{
+		Name:     "postgres",
+		Use:      sqle.UsePostgres,
+		Varchar:  "varchar",
+		Int:      "int",
+		Datetime: "timestamp",
+		Text:     "text",
+	}
+
+	// Oracle is the dialect of Oracle Database 23ai+, which supports `CREATE TABLE IF NOT EXISTS`. Identifiers are not
+	// quoted, so they are stored in upper case and matched case-insensitively. Empty strings are stored as "-", because
+	// Oracle treats '' as NULL.
+	Oracle = Dialect{
+		Name:     "oracle",
+		Use:      useOracle,
+		Varchar:  "varchar2",
+		Int:      "number(10)",
+		Datetime: "timestamp",
+		Text:     "clob",
+		Empty:    "-",
+	}
+)
+
+// useOracle applies parameterize option of Oracle on b, and leaves identifiers unquoted.
+func useOracle(b *sqle.Builder) {
+	sqle.UseOracle(b)
+	b.Quote = ""
+}
+
+type columnType int
+
+const (
+	varcharColumn columnType = iota
+	intColumn
+	datetimeColumn
+	textColumn
+)
+
+// column is a column definition of bookkeeping table
+type column struct {
+	Name    string
+	Type    columnType
+	Size    int
+	Default string
+	Null    bool
+}
+
+var migrationsColumns = []column{
+	{Name: "checksum", Type: varcharColumn, Size: 32},
+	{Name: "module", Type: varcharColumn, Size: 45},
+	{Name: "version", Type: varcharColumn, Size: 45},
+	{Name: "name", Type: varcharColumn, Size: 45},
+	{Name: "rank", Type: intColumn, Default: "0"},
+	{Name: "migrated_on", Type: datetimeColumn},
+	{Name: "execution_time", Type: varcharColumn, Size: 25},
+	{Name: "scripts", Type: textColumn},
+	{Name: "tenant", Type: varcharColumn, Size: 45, Default: "''"},
+}
+
+var rotationsColumns = []column{
+	{Name: "checksum", Type: varcharColumn, Size: 32},
+	{Name: "rotated_name", Type: varcharColumn, Size: 10},
+	{Name: "name", Type: varcharColumn, Size: 45},
+	{Name: "rotated_on", Type: datetimeColumn},
+	{Name: "execution_time", Type: varcharColumn, Size: 25},
+	{Name: "pruned_on", Type: datetimeColumn, Null: true},
+	{Name: "prune_action", Type: varcharColumn, Size: 10, Null: true},
+}
+
+var progressColumns = []column{
+	{Name: "checksum", Type: varcharColumn, Size: 32},
+	{Name: "step", Type: intColumn},
+	{Name: "executed_on", Type: datetimeColumn},
+}
+
+// builder creates a Builder with quote and parameterize options of the dialect.
+func (d Dialect) builder(cmd ...string) *sqle.Builder {
+	b := sqle.New(cmd...)
+	if d.Use != nil {
+		d.Use(b)
+	}
+	return b
+}
+
+// value returns the stored value of s, it is Empty if s is empty.
+func (d Dialect) value(s string) string {
+	if s == "" {
+		return d.Empty
+	}
+	return s
+}
+
+// scan returns the string of stored value s.
+func (d Dialect) scan(s string) string {
+	if d.Empty != "" && s == d.Empty {
+		return ""
+	}
+	return s
+}
+
+// quote escapes identifier with the quote of the dialect.
+func (d Dialect) quote(name string) string {
+	q := d.builder().Quote
+	return q + name + q
+}
+
+func (d Dialect) columnType(c column) string {
+	switch c.Type {
+	case intColumn:
+		return d.Int
+	case datetimeColumn:
+		return d.Datetime
+	case textColumn:
+		return d.Text
+	default:
+		return d.Varchar + "(" + strconv.Itoa(c.Size) + ")"
+	}
+}
+
+// columnDefinition generates the definition of column c in CREATE TABLE and ALTER TABLE statements.
+func (d Dialect) columnDefinition(c column) string {
+	def := d.quote(c.Name) + " " + d.columnType(c)
+	if c.Default == "''" {
+		def += " DEFAULT '" + d.Empty + "'"
+	} else if c.Default != "" {
+		def += " DEFAULT " + c.Default
+	}
+
+	if c.Null {
+		return def + " NULL"
+	}
+
+	return def + " NOT NULL"
+}
+
+// addColumn generates the DDL to add column c on an existing bookkeeping table.
+func (d Dialect) addColumn(table string, c column) string {
+	return "ALTER TABLE " + table + " ADD " + d.columnDefinition(c)
+}
+
+// createTable generates the DDL of a bookkeeping table.
+func (d Dialect) createTable(table string, columns []column, primaryKey ...string) string {
+	var sb strings.Builder
+
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(table)
+	sb.WriteString("(")
+	for _, c := range columns {
+		sb.WriteString(d.columnDefinition(c))
+		sb.WriteString(",")
+	}
+
+	sb.WriteString("PRIMARY KEY (")
+	for i, k := range primaryKey {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(d.quote(k))
+	}
+	sb.WriteString("))")
+
+	return sb.String()
+}
@@ -0,0 +1,188 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+var regexpDollarParam = regexp.MustCompile(`\$(\d+)`)
+
+// pgDriver is a fake driver that accepts Postgres syntax only, and executes statements on SQLite.
+type pgDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *pgDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &pgConn{Conn: conn}, nil
+}
+
+type pgConn struct {
+	driver.Conn
+}
+
+func (c *pgConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.ContainsAny(query, "?`") {
+		return nil, errors.New("pg: syntax error at or near \"?\" or \"`\": " + query)
+	}
+	if strings.Contains(query, " datetime ") {
+		return nil, errors.New("pg: type \"datetime\" does not exist: " + query)
+	}
+	return c.Conn.Prepare(regexpDollarParam.ReplaceAllString(query, "?$1"))
+}
+
+var regexpColonParam = regexp.MustCompile(`:(\w+)`)
+
+// oraDriver is a fake driver that accepts unquoted identifiers and :name parameters only, and executes statements on
+// SQLite. Empty strings are bound as NULL like Oracle does.
+type oraDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *oraDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &oraConn{Conn: conn}, nil
+}
+
+type oraConn struct {
+	driver.Conn
+}
+
+func (c *oraConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.ContainsAny(query, "?`\"") {
+		return nil, errors.New("ORA-00911: invalid character: " + query)
+	}
+	stmt, err := c.Conn.Prepare(regexpColonParam.ReplaceAllString(query, "?"))
+	if err != nil {
+		return nil, err
+	}
+	return &oraStmt{Stmt: stmt}, nil
+}
+
+type oraStmt struct {
+	driver.Stmt
+}
+
+func (s *oraStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.Stmt.Exec(oraValues(args)) // nolint: staticcheck
+}
+
+func (s *oraStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.Stmt.Query(oraValues(args)) // nolint: staticcheck
+}
+
+// oraValues binds empty strings as NULL.
+func oraValues(args []driver.Value) []driver.Value {
+	for i, v := range args {
+		if v == "" {
+			args[i] = nil
+		}
+	}
+	return args
+}
+
+func init() {
+	sql.Register("pgfake", &pgDriver{})
+	sql.Register("orafake", &oraDriver{})
+}
+
+func TestDialect(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/1_create_table_roles.down.sql": &fstest.MapFile{
+			Data: []byte(`DROP TABLE IF EXISTS roles;`),
+		},
+		"daily/daily_logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS daily_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		driver  string
+		dialect Dialect
+		ddl     string
+	}{
+		{
+			name:    "sqlite_should_work",
+			driver:  "sqlite3",
+			dialect: SQLite,
+			ddl:     "CREATE TABLE IF NOT EXISTS sqle_rotations(`checksum` varchar(32) NOT NULL,`rotated_name` varchar(10) NOT NULL,`name` varchar(45) NOT NULL,`rotated_on` datetime NOT NULL,`execution_time` varchar(25) NOT NULL,`pruned_on` datetime NULL,`prune_action` varchar(10) NULL,PRIMARY KEY (`checksum`, `rotated_name`))",
+		},
+		{
+			name:    "postgres_should_work",
+			driver:  "pgfake",
+			dialect: Postgres,
+			ddl:     `CREATE TABLE IF NOT EXISTS sqle_rotations("checksum" varchar(32) NOT NULL,"rotated_name" varchar(10) NOT NULL,"name" varchar(45) NOT NULL,"rotated_on" timestamp NOT NULL,"execution_time" varchar(25) NOT NULL,"pruned_on" timestamp NULL,"prune_action" varchar(10) NULL,PRIMARY KEY ("checksum", "rotated_name"))`,
+		},
+		{
+			name:    "oracle_should_work",
+			driver:  "orafake",
+			dialect: Oracle,
+			ddl:     `CREATE TABLE IF NOT EXISTS sqle_rotations(checksum varchar2(32) NOT NULL,rotated_name varchar2(10) NOT NULL,name varchar2(45) NOT NULL,rotated_on timestamp NOT NULL,execution_time varchar2(25) NOT NULL,pruned_on timestamp NULL,prune_action varchar2(10) NULL,PRIMARY KEY (checksum, rotated_name))`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.ddl, test.dialect.createTable("sqle_rotations", rotationsColumns, "checksum", "rotated_name"))
+
+			d, err := sql.Open(test.driver, "file::memory:")
+			require.NoError(t, err)
+			defer d.Close()
+			d.SetMaxOpenConns(1)
+
+			m := New(sqle.Open(d))
+			m.now = func() time.Time {
+				return time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+			}
+			require.NoError(t, m.Discover(fsys, WithModule("tests"), WithDialect(test.dialect)))
+			require.NoError(t, m.Init(context.TODO()))
+			require.NoError(t, m.Migrate(context.TODO()))
+			require.NoError(t, m.Migrate(context.TODO()))
+			require.NoError(t, m.Verify(context.TODO()))
+			require.NoError(t, m.Rotate(context.TODO()))
+			require.NoError(t, m.Rotate(context.TODO()))
+
+			steps, err := m.Plan(context.TODO())
+			require.NoError(t, err)
+			require.Len(t, steps, 1)
+			require.Equal(t, MigrationStatusExecuted, steps[0].Status)
+
+			require.NoError(t, m.Rollback(context.TODO(), ""))
+
+			steps, err = m.Plan(context.TODO())
+			require.NoError(t, err)
+			require.Equal(t, MigrationStatusNew, steps[0].Status)
+		})
+	}
+
+	t.Run("mysql_dialect_should_not_work_on_postgres", func(t *testing.T) {
+		d, err := sql.Open("pgfake", "file::memory:")
+		require.NoError(t, err)
+		defer d.Close()
+
+		m := New(sqle.Open(d))
+		require.NoError(t, m.Discover(fsys))
+		require.ErrorContains(t, m.Init(context.TODO()), "pg: ")
+	})
+}
@@ -0,0 +1,190 @@
+package migrate
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// DriftKind is the kind of a schema drift.
+type DriftKind int
+
+const (
+	DriftMissing    DriftKind = iota // table, column or index is missing on the database
+	DriftUnexpected                  // table, column or index is not expected on the database
+	DriftChanged                     // column type or nullability, or index columns or uniqueness are changed
+)
+
+func (k DriftKind) String() string {
+	switch k {
+	case DriftMissing:
+		return "missing"
+	case DriftUnexpected:
+		return "unexpected"
+	case DriftChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// Drift is a difference between the expected schema and the live schema of a database.
+type Drift struct {
+	DB     int // index of database in Migrator
+	Kind   DriftKind
+	Table  string
+	Column string // it is empty if the drift is not on a column
+	Index  string // it is empty if the drift is not on an index
+
+	Expected string
+	Actual   string
+}
+
+func (d Drift) String() string {
+	target := d.Table
+	if d.Column != "" {
+		target += "." + d.Column
+	} else if d.Index != "" {
+		target += " index " + d.Index
+	}
+
+	if d.Kind == DriftChanged {
+		return "db-" + strconv.Itoa(d.DB) + " " + target + " changed: " + d.Expected + " => " + d.Actual
+	}
+
+	return "db-" + strconv.Itoa(d.DB) + " " + target + " " + d.Kind.String()
+}
+
+// Drift compares live schema of all databases with expected schema, which is usually committed by WriteSchema.
+// If expected is nil, the schema of first database is expected on other databases.
+func (m *Migrator) Drift(ctx context.Context, expected *Schema) ([]Drift, error) {
+	items, err := m.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	var drifts []Drift
+	start := 0
+	if expected == nil {
+		expected = &items[0]
+		start = 1
+	}
+
+	for i := start; i < len(items); i++ {
+		for _, d := range DiffSchema(*expected, items[i]) {
+			d.DB = i
+			drifts = append(drifts, d)
+		}
+	}
+
+	return drifts, nil
+}
+
+// DiffSchema returns drifts from expected to actual.
+func DiffSchema(expected, actual Schema) []Drift {
+	var drifts []Drift
+
+	tables := make(map[string]Table, len(actual.Tables))
+	for _, t := range actual.Tables {
+		tables[t.Name] = t
+	}
+
+	for _, et := range expected.Tables {
+		at, ok := tables[et.Name]
+		if !ok {
+			drifts = append(drifts, Drift{Kind: DriftMissing, Table: et.Name})
+			continue
+		}
+		delete(tables, et.Name)
+
+		drifts = append(drifts, diffColumns(et, at)...)
+		drifts = append(drifts, diffIndexes(et, at)...)
+	}
+
+	for _, at := range actual.Tables {
+		if _, ok := tables[at.Name]; ok {
+			drifts = append(drifts, Drift{Kind: DriftUnexpected, Table: at.Name})
+		}
+	}
+
+	return drifts
+}
+
+func diffColumns(et, at Table) []Drift {
+	var drifts []Drift
+
+	columns := make(map[string]Column, len(at.Columns))
+	for _, c := range at.Columns {
+		columns[c.Name] = c
+	}
+
+	for _, ec := range et.Columns {
+		ac, ok := columns[ec.Name]
+		if !ok {
+			drifts = append(drifts, Drift{Kind: DriftMissing, Table: et.Name, Column: ec.Name})
+			continue
+		}
+		delete(columns, ec.Name)
+
+		if ec != ac {
+			drifts = append(drifts, Drift{Kind: DriftChanged, Table: et.Name, Column: ec.Name, Expected: formatColumn(ec), Actual: formatColumn(ac)})
+		}
+	}
+
+	for _, ac := range at.Columns {
+		if _, ok := columns[ac.Name]; ok {
+			drifts = append(drifts, Drift{Kind: DriftUnexpected, Table: et.Name, Column: ac.Name})
+		}
+	}
+
+	return drifts
+}
+
+func diffIndexes(et, at Table) []Drift {
+	var drifts []Drift
+
+	indexes := make(map[string]Index, len(at.Indexes))
+	for _, it := range at.Indexes {
+		indexes[it.Name] = it
+	}
+
+	for _, ei := range et.Indexes {
+		ai, ok := indexes[ei.Name]
+		if !ok {
+			drifts = append(drifts, Drift{Kind: DriftMissing, Table: et.Name, Index: ei.Name})
+			continue
+		}
+		delete(indexes, ei.Name)
+
+		if formatIndex(ei) != formatIndex(ai) {
+			drifts = append(drifts, Drift{Kind: DriftChanged, Table: et.Name, Index: ei.Name, Expected: formatIndex(ei), Actual: formatIndex(ai)})
+		}
+	}
+
+	for _, ai := range at.Indexes {
+		if _, ok := indexes[ai.Name]; ok {
+			drifts = append(drifts, Drift{Kind: DriftUnexpected, Table: et.Name, Index: ai.Name})
+		}
+	}
+
+	return drifts
+}
+
+func formatColumn(c Column) string {
+	if c.Nullable {
+		return c.Type + " NULL"
+	}
+	return c.Type + " NOT NULL"
+}
+
+func formatIndex(i Index) string {
+	s := "(" + strings.Join(i.Columns, ", ") + ")"
+	if i.Unique {
+		return "UNIQUE " + s
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"bufio"
+	"strings"
+)
+
+// parseHeaders parses leading comment lines like `/* key: value */` of script into a lowercase key map.
+func parseHeaders(script string) map[string]string {
+	headers := make(map[string]string)
+
+	s := bufio.NewScanner(strings.NewReader(script))
+	s.Split(bufio.ScanLines)
+
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(l, "/*") || !strings.HasSuffix(l, "*/") {
+			break
+		}
+
+		l = strings.TrimSpace(l[2 : len(l)-2])
+		k, v, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+
+		headers[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+
+	return headers
+}
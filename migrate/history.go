@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yaitoo/sqle"
+)
+
+// History is the migration history of a database.
+type History struct {
+	DB     int    // index of database in Migrator
+	Tenant string // tenant of the history, it is empty if tenants are not configured
+
+	// Executed are versions and scripts that are recorded in sqle_migrations, MigratedOn and ExecutionTime are populated.
+	// Scripts that have been removed from filesystem are included too.
+	Executed []Semver
+
+	// Pending are discovered versions and scripts that have not been executed.
+	Pending []Semver
+}
+
+// History returns executed and pending versions of current module on all databases, and for every tenant if tenants
+// are configured. MigratedOn is scanned into time.Time, so parseTime=true should be set in the DSN of MySQL.
+func (m *Migrator) History(ctx context.Context) ([]History, error) {
+	items := make([]History, 0, len(m.dbs))
+	for i, db := range m.dbs {
+		err := m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+			h, err := tm.getHistory(ctx, i, db)
+			if err != nil {
+				return err
+			}
+			items = append(items, h)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+func (m *Migrator) getHistory(ctx context.Context, i int, db *sqle.DB) (History, error) {
+	h := History{DB: i, Tenant: m.tenant}
+
+	rows, err := db.QueryBuilder(ctx, m.dialect.builder("SELECT checksum, version, name, "+m.dialect.quote("rank")+", scripts, migrated_on, execution_time FROM sqle_migrations WHERE module = {module} AND tenant = {tenant}").
+		Param("module", m.dialect.value(m.module)).
+		Param("tenant", m.dialect.value(m.tenant)))
+	if err != nil {
+		return h, err
+	}
+	defer rows.Close()
+
+	executed := make(map[string]bool)
+	for rows.Next() {
+		var (
+			version       string
+			mi            Migration
+			migratedOn    time.Time
+			executionTime string
+		)
+
+		err = rows.Scan(&mi.Checksum, &version, &mi.Name, &mi.Rank, &mi.Scripts, &migratedOn, &executionTime)
+		if err != nil {
+			return h, err
+		}
+
+		mi.Scripts = m.dialect.scan(mi.Scripts)
+		mi.MigratedOn = &migratedOn
+		mi.ExecutionTime, _ = time.ParseDuration(executionTime)
+
+		executed[mi.Checksum] = true
+		executed[version+"/"+strconv.Itoa(mi.Rank)+"_"+mi.Name] = true
+		h.Executed = appendMigration(h.Executed, version, mi)
+	}
+
+	if err = rows.Err(); err != nil {
+		return h, err
+	}
+
+	for _, v := range m.Versions {
+		for _, mi := range v.Migrations {
+			// modified scripts have been executed
+			if executed[mi.Checksum] || executed[v.Name+"/"+strconv.Itoa(mi.Rank)+"_"+mi.Name] {
+				continue
+			}
+
+			h.Pending = appendMigration(h.Pending, v.Name, mi)
+		}
+	}
+
+	sortVersions(h.Executed)
+	sortVersions(h.Pending)
+
+	return h, nil
+}
+
+// appendMigration appends migration mi into version of versions.
+func appendMigration(versions []Semver, version string, mi Migration) []Semver {
+	for i, v := range versions {
+		if v.Name == version {
+			versions[i].Migrations = append(v.Migrations, mi)
+			return versions
+		}
+	}
+
+	v, err := parseSemver(version)
+	if err != nil {
+		v = Semver{Name: version}
+	}
+	v.Migrations = []Migration{mi}
+
+	return append(versions, v)
+}
+
+// sortVersions sorts versions and their migrations by semver and rank.
+func sortVersions(versions []Semver) {
+	sort.Slice(versions, func(i, j int) bool {
+		return lessSemver(versions[i], versions[j])
+	})
+
+	for i := range versions {
+		sort.Sort(&versions[i])
+	}
+}
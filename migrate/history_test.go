@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestHistory(t *testing.T) {
+	d0, clean0, err := createSqlite3()
+	defer clean0()
+	require.NoError(t, err)
+
+	d1, clean1, err := createSqlite3()
+	defer clean1()
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/2_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS users (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.2.0/1_create_table_logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS logs (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	// only db-0 is migrated to 0.1.0
+	m := New(sqle.Open(d0))
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": fsys["0.1.0/1_create_table_roles.sql"],
+		"0.1.0/2_create_table_users.sql": fsys["0.1.0/2_create_table_users.sql"],
+	}, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+
+	m = New(sqle.Open(d0), sqle.Open(d1))
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+
+	items, err := m.History(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	h := items[0]
+	require.Equal(t, 0, h.DB)
+	require.Len(t, h.Executed, 1)
+	require.Equal(t, "0.1.0", h.Executed[0].Name)
+	require.Len(t, h.Executed[0].Migrations, 2)
+
+	mi := h.Executed[0].Migrations[0]
+	require.Equal(t, "create_table_roles", mi.Name)
+	require.Equal(t, 1, mi.Rank)
+	require.NotNil(t, mi.MigratedOn)
+	require.WithinDuration(t, time.Now(), *mi.MigratedOn, time.Minute)
+	require.Greater(t, mi.ExecutionTime, time.Duration(0))
+	require.Equal(t, "create_table_users", h.Executed[0].Migrations[1].Name)
+
+	require.Len(t, h.Pending, 1)
+	require.Equal(t, "0.2.0", h.Pending[0].Name)
+	require.Nil(t, h.Pending[0].Migrations[0].MigratedOn)
+
+	h = items[1]
+	require.Equal(t, 1, h.DB)
+	require.Empty(t, h.Executed)
+	require.Len(t, h.Pending, 2)
+	require.Equal(t, "0.1.0", h.Pending[0].Name)
+	require.Len(t, h.Pending[0].Migrations, 2)
+	require.Equal(t, "0.2.0", h.Pending[1].Name)
+}
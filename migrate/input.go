@@ -0,0 +1,116 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yaitoo/sqle"
+)
+
+// reservedInputs are expanded by Migrator when scripts are executed.
+var reservedInputs = map[string]bool{
+	"rotate": true,
+	"tenant": true,
+}
+
+// expandInputs replaces <name> input tokens in script with inputs. Reserved inputs and {name} param tokens are kept
+// as they are, and string literals and comments are not expanded. eg '<b>hi</b>'
+func expandInputs(script string, inputs map[string]string) (string, error) {
+	var (
+		sb    strings.Builder
+		start int // start of code that is not expanded yet
+	)
+
+	n := len(script)
+	for i := 0; i < n; {
+		var end int
+
+		c := script[i]
+		switch {
+		case c == '\'':
+			end = skipQuoted(script, i, c)
+		case c == '-' && i+1 < n && script[i+1] == '-':
+			end = skipLine(script, i)
+		case c == '/' && i+1 < n && script[i+1] == '*':
+			end = skipBlockComment(script, i)
+		default:
+			i++
+			continue
+		}
+
+		if err := expandCode(&sb, script[start:i], inputs); err != nil {
+			return "", err
+		}
+
+		sb.WriteString(script[i:end])
+		i, start = end, end
+	}
+
+	if err := expandCode(&sb, script[start:], inputs); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// expandCode writes code into sb with its input tokens replaced by inputs.
+func expandCode(sb *strings.Builder, code string, inputs map[string]string) error {
+	tz := sqle.Tokenize(code)
+
+	for _, t := range tz.Tokens {
+		switch t.Type() {
+		case sqle.InputToken:
+			n := t.String()
+			if reservedInputs[n] {
+				sb.WriteString("<" + n + ">")
+				continue
+			}
+
+			v, ok := inputs[n]
+			if !ok {
+				return fmt.Errorf("%w: <%s>", ErrUndefinedInput, n)
+			}
+			sb.WriteString(v)
+		case sqle.ParamToken:
+			sb.WriteString("{" + t.String() + "}")
+		default:
+			sb.WriteString(t.String())
+		}
+	}
+
+	return nil
+}
+
+// loadInputs expands inputs in discovered scripts and rotations. Checksums are computed on raw files, so changing
+// inputs doesn't modify executed scripts.
+func (m *Migrator) loadInputs() error {
+	var err error
+	for _, v := range m.Versions {
+		for i, mi := range v.Migrations {
+			if mi.Func != nil {
+				continue
+			}
+
+			v.Migrations[i].Scripts, err = expandInputs(mi.Scripts, m.inputs)
+			if err != nil {
+				return fmt.Errorf("%w in v%s %s", err, v.Name, mi.File)
+			}
+
+			v.Migrations[i].DownScripts, err = expandInputs(mi.DownScripts, m.inputs)
+			if err != nil {
+				return fmt.Errorf("%w in v%s %s", err, v.Name, mi.File)
+			}
+		}
+	}
+
+	for _, g := range m.getRotations() {
+		for i, r := range g.Rotations {
+			g.Rotations[i].Script, err = expandInputs(r.Script, m.inputs)
+			if err != nil {
+				return fmt.Errorf("%w in %s", err, r.Name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestExpandInputs(t *testing.T) {
+	inputs := map[string]string{"engine": "InnoDB", "charset": "utf8mb4"}
+
+	tests := []struct {
+		name    string
+		script  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "inputs",
+			script: "CREATE TABLE roles (id int) ENGINE=<engine> DEFAULT CHARSET=<charset>;",
+			want:   "CREATE TABLE roles (id int) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;",
+		},
+		{
+			name:   "reserved_inputs_and_params",
+			script: "CREATE TABLE <tenant>.logs<rotate> (id int) ENGINE=<engine>; SELECT '{id}';",
+			want:   "CREATE TABLE <tenant>.logs<rotate> (id int) ENGINE=InnoDB; SELECT '{id}';",
+		},
+		{
+			name:   "literals_and_comments",
+			script: "-- <engine> is set by inputs\nINSERT INTO t VALUES ('<b>hi</b>', 'it''s <i>'); /* <tag> */ SELECT '<b>' FROM <charset>;",
+			want:   "-- <engine> is set by inputs\nINSERT INTO t VALUES ('<b>hi</b>', 'it''s <i>'); /* <tag> */ SELECT '<b>' FROM utf8mb4;",
+		},
+		{
+			name:    "undefined_input",
+			script:  "CREATE TABLE <schema>.roles (id int);",
+			wantErr: ErrUndefinedInput,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := expandInputs(test.script, inputs)
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestMigrateInputs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS <prefix>roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"monthly/logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS <prefix>logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.ErrorIs(t, m.Discover(fsys, WithModule("tests")), ErrUndefinedInput)
+
+	m = New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithInputs(map[string]string{"prefix": "app_"})))
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+	require.NoError(t, m.Rotate(context.TODO()))
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name LIKE 'app_%'").Scan(&n))
+	require.Equal(t, 3, n)
+
+	// checksum is computed on raw file, so changing inputs doesn't modify executed scripts
+	m = New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithInputs(map[string]string{"prefix": "new_"})))
+	require.NoError(t, m.Verify(context.TODO()))
+
+	// tags in string literals are not inputs
+	m = New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_seed.sql": &fstest.MapFile{Data: []byte(`INSERT INTO t VALUES ('<b>hi</b>');`)},
+	}, WithModule("seed"), WithLogger(DiscardLogger)))
+}
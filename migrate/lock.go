@@ -0,0 +1,229 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yaitoo/sqle"
+)
+
+var (
+	// DefaultLockTimeout is the default duration to wait for the migration lock
+	DefaultLockTimeout = 10 * time.Minute
+	// DefaultLockTTL is the default duration before a migration lock is considered stale and can be taken over
+	DefaultLockTTL = 5 * time.Minute
+
+	lockRetryInterval = 200 * time.Millisecond
+)
+
+var lockColumns = []column{
+	{Name: "name", Type: varcharColumn, Size: 45},
+	{Name: "owner", Type: varcharColumn, Size: 64},
+	{Name: "locked_on", Type: datetimeColumn},
+	{Name: "expires_on", Type: datetimeColumn},
+}
+
+// newLockOwner creates an unique owner name for the migration lock
+func newLockOwner() string {
+	host, _ := os.Hostname()
+	buf := make([]byte, 4)
+	rand.Read(buf) // nolint: errcheck
+
+	owner := fmt.Sprintf("%s:%d:%x", host, os.Getpid(), buf)
+	if len(owner) > 64 {
+		owner = owner[len(owner)-64:]
+	}
+	return owner
+}
+
+// getLockName returns the lock name of current module
+func (m *Migrator) getLockName() string {
+	name := "sqle:" + m.module
+	if len(name) > 45 {
+		name = name[0:45]
+	}
+	return name
+}
+
+// withLock acquires the migration lock on db, and releases it after fn is completed. The lock is refreshed every
+// TTL/3 while fn is running, and ctx of fn is canceled with ErrLockLost if the lock is taken over by others.
+func (m *Migrator) withLock(ctx context.Context, db *sqle.DB, fn func(ctx context.Context) error) error {
+	err := m.lock(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	// lock should be released even if ctx is canceled
+	defer m.unlock(context.Background(), db) // nolint: errcheck
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lost := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.heartbeat(ctx, db, stop, lost, cancel)
+	}()
+
+	err = fn(ctx)
+	close(stop)
+	<-done
+
+	select {
+	case <-lost:
+		return errors.Join(ErrLockLost, err)
+	default:
+		return err
+	}
+}
+
+// heartbeat refreshes the migration lock every TTL/3 until stop is closed. lost is closed and cancel is called once
+// the lock is lost. Other errors are ignored, and it is refreshed again on next tick.
+func (m *Migrator) heartbeat(ctx context.Context, db *sqle.DB, stop, lost chan struct{}, cancel context.CancelFunc) {
+	interval := m.lockTTL / 3
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if errors.Is(m.refreshLock(ctx, db), ErrLockLost) {
+				close(lost)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// lock waits for the migration lock on db until it is acquired, or lock timeout is reached.
+// A lock that is expired is taken over.
+func (m *Migrator) lock(ctx context.Context, db *sqle.DB) error {
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		ok, err := m.tryLock(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return ErrLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func (m *Migrator) tryLock(ctx context.Context, db *sqle.DB) (bool, error) {
+	name := m.getLockName()
+	now := time.Now().UTC()
+
+	// take over the lock if it is held by current owner or it is expired. RowsAffected can't tell if it is taken over,
+	// because MySQL reports changed rows only, and nothing is changed in the same second of datetime.
+	_, err := db.ExecBuilder(ctx, m.dialect.builder("UPDATE sqle_migration_lock SET owner = {owner}, locked_on = {now}, expires_on = {expires_on} WHERE name = {name} AND (owner = {owner} OR expires_on < {now})").
+		Param("owner", m.lockOwner).
+		Param("now", now).
+		Param("expires_on", now.Add(m.lockTTL)).
+		Param("name", name))
+	if err != nil {
+		return false, err
+	}
+
+	owner, err := m.getLockOwner(ctx, db)
+	if err == nil {
+		return owner == m.lockOwner, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	cmd := m.dialect.builder()
+	cmd.Insert("sqle_migration_lock").
+		Set("name", name).
+		Set("owner", m.lockOwner).
+		Set("locked_on", now).
+		Set("expires_on", now.Add(m.lockTTL)).
+		End()
+
+	_, err = db.ExecBuilder(ctx, cmd)
+	if err == nil {
+		return true, nil
+	}
+
+	// lock is held by other owner if it exists
+	owner, e := m.getLockOwner(ctx, db)
+	if e == nil {
+		return owner == m.lockOwner, nil
+	}
+
+	if errors.Is(e, sql.ErrNoRows) {
+		return false, err
+	}
+
+	return false, e
+}
+
+// getLockOwner returns the owner of the migration lock, or sql.ErrNoRows if it is not held by anyone.
+func (m *Migrator) getLockOwner(ctx context.Context, db *sqle.DB) (string, error) {
+	var owner string
+	err := db.QueryRowBuilder(ctx, m.dialect.builder("SELECT owner FROM sqle_migration_lock WHERE name = {name}").
+		Param("name", m.getLockName())).Scan(&owner)
+	return owner, err
+}
+
+// refreshLock extends the expiry of the migration lock held by current owner. It returns ErrLockLost if the lock is
+// taken over by others.
+func (m *Migrator) refreshLock(ctx context.Context, db *sqle.DB) error {
+	// RowsAffected is 0 on MySQL if it is refreshed in the same second, so the owner is checked instead
+	_, err := db.ExecBuilder(ctx, m.dialect.builder("UPDATE sqle_migration_lock SET expires_on = {expires_on} WHERE name = {name} AND owner = {owner}").
+		Param("expires_on", time.Now().UTC().Add(m.lockTTL)).
+		Param("name", m.getLockName()).
+		Param("owner", m.lockOwner))
+	if err != nil {
+		return err
+	}
+
+	owner, err := m.getLockOwner(ctx, db)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrLockLost
+		}
+		return err
+	}
+
+	if owner != m.lockOwner {
+		return ErrLockLost
+	}
+
+	return nil
+}
+
+// unlock releases the migration lock held by current owner.
+func (m *Migrator) unlock(ctx context.Context, db *sqle.DB) error {
+	_, err := db.ExecBuilder(ctx, m.dialect.builder("DELETE FROM sqle_migration_lock WHERE name = {name} AND owner = {owner}").
+		Param("name", m.getLockName()).
+		Param("owner", m.lockOwner))
+	return err
+}
@@ -0,0 +1,199 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+// mysqlDriver is a fake driver that reports changed rows of UPDATE like MySQL does without clientFoundRows. It
+// reports nothing is changed, as it is in the same second of datetime columns.
+type mysqlDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *mysqlDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlConn{Conn: conn}, nil
+}
+
+type mysqlConn struct {
+	driver.Conn
+}
+
+func (c *mysqlConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlStmt{Stmt: stmt, update: strings.HasPrefix(query, "UPDATE ")}, nil
+}
+
+type mysqlStmt struct {
+	driver.Stmt
+	update bool
+}
+
+func (s *mysqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	r, err := s.Stmt.Exec(args) // nolint: staticcheck
+	if err != nil || !s.update {
+		return r, err
+	}
+	return unchangedResult{Result: r}, nil
+}
+
+type unchangedResult struct {
+	driver.Result
+}
+
+func (unchangedResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
+func init() {
+	sql.Register("mysqlfake", &mysqlDriver{})
+}
+
+func TestLock(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m1 := New(db)
+	require.NoError(t, m1.Discover(fsys, WithModule("tests")))
+	require.NoError(t, m1.Init(context.TODO()))
+
+	m2 := New(db)
+	require.NoError(t, m2.Discover(fsys, WithModule("tests"), WithLockTimeout(100*time.Millisecond)))
+
+	t.Run("lock_should_be_exclusive", func(t *testing.T) {
+		require.NoError(t, m1.lock(context.TODO(), db))
+		// lock is reentrant for its owner
+		require.NoError(t, m1.lock(context.TODO(), db))
+
+		require.ErrorIs(t, m2.Migrate(context.TODO()), ErrLockTimeout)
+		require.ErrorIs(t, m2.Rotate(context.TODO()), ErrLockTimeout)
+		require.ErrorIs(t, m2.Rollback(context.TODO(), ""), ErrLockTimeout)
+
+		require.NoError(t, m1.unlock(context.TODO(), db))
+		require.NoError(t, m2.Migrate(context.TODO()))
+
+		// lock is released after migration
+		require.NoError(t, m1.lock(context.TODO(), db))
+		require.NoError(t, m1.unlock(context.TODO(), db))
+	})
+
+	t.Run("stale_lock_should_be_taken_over", func(t *testing.T) {
+		m1.lockTTL = time.Millisecond
+		require.NoError(t, m1.lock(context.TODO(), db))
+		time.Sleep(10 * time.Millisecond)
+
+		require.NoError(t, m2.lock(context.TODO(), db))
+		require.ErrorIs(t, m1.refreshLock(context.TODO(), db), ErrLockLost)
+		require.NoError(t, m2.refreshLock(context.TODO(), db))
+		require.NoError(t, m2.unlock(context.TODO(), db))
+	})
+
+	t.Run("canceled_context_should_stop_waiting", func(t *testing.T) {
+		m1.lockTTL = DefaultLockTTL
+		require.NoError(t, m1.lock(context.TODO(), db))
+		defer m1.unlock(context.TODO(), db) // nolint: errcheck
+
+		m2.lockTimeout = time.Minute
+		ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, m2.lock(ctx, db), context.DeadlineExceeded)
+	})
+	t.Run("lock_should_be_refreshed_while_running", func(t *testing.T) {
+		m1.lockTTL = 60 * time.Millisecond
+		m2.lockTimeout = 10 * time.Millisecond
+		defer func() { m1.lockTTL = DefaultLockTTL }()
+
+		err := m1.withLock(context.TODO(), db, func(ctx context.Context) error {
+			// it outlives TTL
+			time.Sleep(200 * time.Millisecond)
+			require.NoError(t, ctx.Err())
+			require.ErrorIs(t, m2.lock(context.TODO(), db), ErrLockTimeout)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("lost_lock_should_cancel_running", func(t *testing.T) {
+		m1.lockTTL = 60 * time.Millisecond
+		defer func() { m1.lockTTL = DefaultLockTTL }()
+
+		err := m1.withLock(context.TODO(), db, func(ctx context.Context) error {
+			// it is taken over by others
+			_, err := db.Exec("UPDATE sqle_migration_lock SET owner = ?", "others")
+			require.NoError(t, err)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		})
+		require.ErrorIs(t, err, ErrLockLost)
+		require.ErrorIs(t, err, context.Canceled)
+
+		_, err = db.Exec("DELETE FROM sqle_migration_lock")
+		require.NoError(t, err)
+	})
+}
+
+func TestLockUnchangedRows(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"daily/daily_logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS daily_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	d, err := sql.Open("mysqlfake", filepath.Join(t.TempDir(), "lock.db"))
+	require.NoError(t, err)
+	defer d.Close()
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+
+	// lock is refreshed right after it is acquired
+	require.NoError(t, m.Migrate(context.TODO()))
+	require.NoError(t, m.Rotate(context.TODO()))
+
+	require.NoError(t, m.lock(context.TODO(), db))
+	require.NoError(t, m.lock(context.TODO(), db))
+	require.NoError(t, m.refreshLock(context.TODO(), db))
+
+	_, err = db.Exec("UPDATE sqle_migration_lock SET owner = ?", "others")
+	require.NoError(t, err)
+	require.ErrorIs(t, m.refreshLock(context.TODO(), db), ErrLockLost)
+
+	ok, err := m.tryLock(context.TODO(), db)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,213 @@
+package migrate
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType is the type of event that is emitted by Migrator.
+type EventType int
+
+const (
+	EventStarted           EventType = iota // action is started on a database
+	EventVersionStarted                     // version is started
+	EventVersionCompleted                   // version is committed
+	EventScriptApplied                      // script is executed
+	EventScriptReverted                     // script is reverted by its down script
+	EventScriptExecuted                     // script is skipped because it has been executed
+	EventScriptModified                     // script is skipped because it has been modified after it was executed
+	EventScriptPending                      // script has not been executed yet
+	EventRotationStarted                    // rotation is started
+	EventRotationCreated                    // rotated table is created
+	EventRotationExists                     // rotated table is skipped because it has been created
+	EventRotationCompleted                  // rotation is committed
+	EventRotationDropped                    // expired rotated table is dropped
+	EventRotationArchived                   // expired rotated table is renamed to archive
+	EventScriptRepaired                     // checksum of modified script is rewritten
+	EventScriptBaselined                    // script is marked as executed without being executed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventStarted:
+		return "started"
+	case EventVersionStarted:
+		return "version_started"
+	case EventVersionCompleted:
+		return "version_completed"
+	case EventScriptApplied:
+		return "script_applied"
+	case EventScriptReverted:
+		return "script_reverted"
+	case EventScriptExecuted:
+		return "script_executed"
+	case EventScriptModified:
+		return "script_modified"
+	case EventScriptPending:
+		return "script_pending"
+	case EventRotationStarted:
+		return "rotation_started"
+	case EventRotationCreated:
+		return "rotation_created"
+	case EventRotationExists:
+		return "rotation_exists"
+	case EventRotationCompleted:
+		return "rotation_completed"
+	case EventRotationDropped:
+		return "rotation_dropped"
+	case EventRotationArchived:
+		return "rotation_archived"
+	case EventScriptRepaired:
+		return "script_repaired"
+	case EventScriptBaselined:
+		return "script_baselined"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a typed event that is emitted by Migrator on each step.
+type Event struct {
+	Type   EventType
+	Action string // migrate, rotate, prune, rollback, repair, baseline or status
+	Module string
+	Tenant string
+	DB     int // index of database in Migrator
+	DBs    int // number of databases in Migrator
+
+	Version string
+	Name    string // name of script or rotation
+	Rank    int
+	Index   int // 1-based position of script in version, or rotated table in rotation
+	Total   int
+
+	// Rotations are the table and its rotated tables that script is applied on, or the rotated table created or pruned by rotation.
+	Rotations []string
+	Duration  time.Duration
+}
+
+// Logger receives events emitted by Migrator.
+type Logger interface {
+	Log(ctx context.Context, e Event)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as Logger.
+type LoggerFunc func(ctx context.Context, e Event)
+
+// Log calls f(ctx, e).
+func (f LoggerFunc) Log(ctx context.Context, e Event) {
+	f(ctx, e)
+}
+
+// DiscardLogger drops all events.
+var DiscardLogger Logger = LoggerFunc(func(context.Context, Event) {})
+
+// NewSlogLogger creates a Logger that writes events as structured records to h.
+func NewSlogLogger(h slog.Handler) Logger {
+	l := slog.New(h)
+	return LoggerFunc(func(ctx context.Context, e Event) {
+		level := slog.LevelInfo
+		if e.Type == EventScriptModified {
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("action", e.Action),
+			slog.String("module", e.Module),
+			slog.Int("db", e.DB),
+		}
+
+		if e.Tenant != "" {
+			attrs = append(attrs, slog.String("tenant", e.Tenant))
+		}
+
+		if e.Version != "" {
+			attrs = append(attrs, slog.String("version", e.Version))
+		}
+
+		if e.Name != "" {
+			attrs = append(attrs, slog.String("name", e.Name))
+		}
+
+		if e.Rank > 0 {
+			attrs = append(attrs, slog.Int("rank", e.Rank))
+		}
+
+		if len(e.Rotations) > 0 {
+			attrs = append(attrs, slog.Any("rotations", e.Rotations))
+		}
+
+		if e.Duration > 0 {
+			attrs = append(attrs, slog.Duration("duration", e.Duration))
+		}
+
+		l.LogAttrs(ctx, level, "migrate: "+e.Type.String(), attrs...)
+	})
+}
+
+// boxLogger writes events as box-drawing tables by standard logger. It is the default Logger of Migrator.
+type boxLogger struct{}
+
+func (boxLogger) Log(_ context.Context, e Event) {
+	w := len(strconv.Itoa(e.Total))
+	switch e.Type {
+	case EventStarted:
+		if e.DBs == 1 {
+			log.Printf("%s: %s\n", e.Action, e.Module)
+		} else {
+			log.Printf("%s db-%v: %s\n", e.Action, e.DB, e.Module)
+		}
+	case EventVersionStarted:
+		if e.Tenant != "" {
+			log.Printf("┌─[ %s: v%s ]\n", e.Tenant, e.Version)
+		} else {
+			log.Printf("┌─[ v%s ]\n", e.Version)
+		}
+	case EventRotationStarted:
+		log.Printf("┌─[ %s ]\n", e.Name)
+	case EventVersionCompleted, EventRotationCompleted:
+		log.Println("└────────────────────────────────────────────────────────────────")
+	case EventScriptExecuted:
+		log.Printf("│ »[%*d/%d] %-35s %-10s [✔]", w, e.Index, e.Total, e.Name, "")
+	case EventScriptModified:
+		log.Printf("│ »[%*d/%d] %-35s %-10s [!]", w, e.Index, e.Total, e.Name, "")
+	case EventScriptPending:
+		log.Printf("│ »[%*d/%d] %-35s %-10s [ ]", w, e.Index, e.Total, e.Name, "")
+	case EventScriptRepaired:
+		log.Printf("│ »[%*d/%d] v%s %-35s [*]", w, e.Index, e.Total, e.Version, e.Name)
+	case EventScriptBaselined:
+		log.Printf("│ »[%*d/%d] v%s %-35s [=]", w, e.Index, e.Total, e.Version, e.Name)
+	case EventScriptApplied, EventScriptReverted:
+		mark := "+"
+		if e.Type == EventScriptReverted {
+			mark = "-"
+		}
+		log.Printf("│ »[%*d/%d] %-35s %-10s [%s]\n", w, e.Index, e.Total, e.Name, round(e.Duration).String(), mark)
+		if len(e.Rotations) > 1 {
+			log.Printf("│ »      %-35s \n", "rotate:")
+			for _, rt := range e.Rotations[1:] {
+				log.Printf("│ »       %s %-35s \n", mark, rt)
+			}
+		}
+	case EventRotationExists:
+		log.Printf("│ »[%*d/%d] %-35s %-10s [✔]", w, e.Index, e.Total, strings.Join(e.Rotations, ","), "")
+	case EventRotationCreated:
+		log.Printf("│ »[%*d/%d] %-35s %-10s [+]\n", w, e.Index, e.Total, strings.Join(e.Rotations, ","), round(e.Duration).String())
+	case EventRotationDropped:
+		log.Printf("│ »[%*d/%d] %-35s %-10s [-]\n", w, e.Index, e.Total, strings.Join(e.Rotations, ","), round(e.Duration).String())
+	case EventRotationArchived:
+		log.Printf("│ »[%*d/%d] %-35s %-10s [~]\n", w, e.Index, e.Total, strings.Join(e.Rotations, ","), round(e.Duration).String())
+	}
+}
+
+// emit sends event e to the logger of Migrator.
+func (m *Migrator) emit(ctx context.Context, e Event) {
+	e.Module = m.module
+	e.Tenant = m.tenant
+	e.DBs = len(m.dbs)
+	m.logger.Log(ctx, e)
+}
@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestLogger(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/2_create_monthly_logs.sql": &fstest.MapFile{
+			Data: []byte(`/* rotate: monthly = 20240201 - 20240201 */
+CREATE TABLE IF NOT EXISTS monthly_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"daily/daily_logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS daily_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	t.Run("events_should_be_emitted", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var events []Event
+		logger := LoggerFunc(func(_ context.Context, e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			e.Duration = 0
+			events = append(events, e)
+		})
+
+		m := New(sqle.Open(d))
+		m.now = func() time.Time {
+			return time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		}
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(logger)))
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Migrate(context.TODO()))
+
+		require.Equal(t, []Event{
+			{Type: EventStarted, Action: "migrate", Module: "tests", DBs: 1},
+			{Type: EventVersionStarted, Action: "migrate", Module: "tests", DBs: 1, Version: "0.1.0"},
+			{Type: EventScriptApplied, Action: "migrate", Module: "tests", DBs: 1, Version: "0.1.0", Name: "create_table_roles", Rank: 1, Index: 1, Total: 2, Rotations: []string{""}},
+			{Type: EventScriptApplied, Action: "migrate", Module: "tests", DBs: 1, Version: "0.1.0", Name: "create_monthly_logs", Rank: 2, Index: 2, Total: 2, Rotations: []string{"", "_202402"}},
+			{Type: EventVersionCompleted, Action: "migrate", Module: "tests", DBs: 1, Version: "0.1.0"},
+		}, events)
+
+		events = nil
+		require.NoError(t, m.Migrate(context.TODO()))
+		require.Equal(t, EventScriptExecuted, events[2].Type)
+		require.Equal(t, EventScriptExecuted, events[3].Type)
+
+		events = nil
+		require.NoError(t, m.Rotate(context.TODO()))
+		require.Equal(t, []Event{
+			{Type: EventStarted, Action: "rotate", Module: "tests", DBs: 1},
+			{Type: EventRotationStarted, Action: "rotate", Module: "tests", DBs: 1, Name: "daily_logs"},
+			{Type: EventRotationCreated, Action: "rotate", Module: "tests", DBs: 1, Name: "daily_logs", Index: 1, Total: 2, Rotations: []string{"_20240201"}},
+			{Type: EventRotationCreated, Action: "rotate", Module: "tests", DBs: 1, Name: "daily_logs", Index: 2, Total: 2, Rotations: []string{"_20240202"}},
+			{Type: EventRotationCompleted, Action: "rotate", Module: "tests", DBs: 1, Name: "daily_logs"},
+		}, events)
+	})
+
+	t.Run("slog_should_work", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		m := New(sqle.Open(d))
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(NewSlogLogger(slog.NewJSONHandler(&buf, nil)))))
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Migrate(context.TODO()))
+
+		require.Contains(t, buf.String(), `"msg":"migrate: script_applied","action":"migrate","module":"tests","db":0,"version":"0.1.0","name":"create_table_roles","rank":1,"rotations":[""]`)
+		require.NotContains(t, buf.String(), "┌─")
+	})
+
+	t.Run("discard_should_work", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+
+		m := New(sqle.Open(d))
+		require.NoError(t, m.Discover(fsys, WithLogger(DiscardLogger)))
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Migrate(context.TODO()))
+	})
+}
@@ -1,7 +1,6 @@
 package migrate
 
 import (
-	"bufio"
 	// skipcq: GSC-G501
 	"crypto/md5"
 	"errors"
@@ -18,6 +17,14 @@ import (
 var (
 	ErrInvalidScriptName  = errors.New("migrate: invalid script name")
 	ErrInvalidRotateRange = errors.New("migrate: invalid rotate range")
+	ErrInvalidRetention   = errors.New("migrate: invalid retention header")
+	ErrInvalidLookAhead   = errors.New("migrate: invalid look-ahead header")
+	ErrUndefinedInput     = errors.New("migrate: undefined input")
+	ErrMigrationModified  = errors.New("migrate: migration is modified")
+	ErrInvalidVersion     = errors.New("migrate: invalid version")
+	ErrMissingDownScript  = errors.New("migrate: missing down script")
+	ErrLockTimeout        = errors.New("migrate: lock timeout")
+	ErrLockLost           = errors.New("migrate: lock lost")
 )
 
 type Semver struct {
@@ -37,11 +44,20 @@ func (s *Semver) Less(i, j int) bool {
 }
 
 type Migration struct {
+	File     string
 	Name     string
 	Rank     int
 	Checksum string
 	Scripts  string
 
+	DownScripts string
+
+	// NoTx is true if the script has `/* tx: none */` header. It is executed outside of transaction statement by statement.
+	NoTx bool
+
+	// Func is the Go migration registered by Register. Scripts is empty if it is not nil.
+	Func MigrateFunc
+
 	Rotate      shardid.TableRotate
 	RotateBegin time.Time
 	RotateEnd   time.Time
@@ -63,6 +79,7 @@ func loadMigration(name string, fsys fs.FS, path string) (Migration, error) {
 		return Migration{}, ErrInvalidScriptName
 	}
 
+	mi.File = name
 	mi.Rank = o
 	mi.Name = matches[2]
 
@@ -78,31 +95,24 @@ func loadMigration(name string, fsys fs.FS, path string) (Migration, error) {
 	mi.Checksum = fmt.Sprintf("%x", h.Sum(nil))
 	mi.Scripts = string(buf)
 
-	s := bufio.NewScanner(strings.NewReader(mi.Scripts))
-	s.Split(bufio.ScanLines)
-
-	if s.Scan() {
-		l := strings.ReplaceAll(s.Text(), " ", "")
-
-		/*rotate:monthly=yyyyMMDD-yyyyMMDD*/
-		if strings.HasPrefix(l, "/*") && strings.HasSuffix(l, "*/") {
-			items := strings.Split(l, ":")
-			if len(items) == 2 && items[0] == "/*rotate" {
-				options := strings.Split(items[1][0:len(items[1])-2], "=")
-				if len(options) == 2 && len(options[1]) == 17 {
-					r := getRotate(options[0])
-					if r != shardid.NoRotate {
-						begin, end, err := getRotateRange(options[1])
-						if err == nil {
-							mi.Rotate = r
-							mi.RotateBegin = begin
-							mi.RotateEnd = end
-						}
-					}
-				}
+	headers := parseHeaders(mi.Scripts)
+
+	/*rotate:monthly=yyyyMMDD-yyyyMMDD*/
+	options := strings.Split(strings.ReplaceAll(headers["rotate"], " ", ""), "=")
+	if len(options) == 2 && len(options[1]) == 17 {
+		r := getRotate(options[0])
+		if r != shardid.NoRotate {
+			begin, end, err := getRotateRange(options[1])
+			if err == nil {
+				mi.Rotate = r
+				mi.RotateBegin = begin
+				mi.RotateEnd = end
 			}
 		}
-
 	}
+
+	/*tx:none*/
+	mi.NoTx = strings.EqualFold(headers["tx"], "none")
+
 	return mi, nil
 }
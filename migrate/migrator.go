@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"log"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -22,6 +22,7 @@ var (
 	regexpChange = regexp.MustCompile(`^(\d+)_([0-9a-z_\-]+)\.`)
 )
 
+// TABLE_MIGRATIONS is the DDL of migrations table on MySQL/SQLite. Init creates it with DDL generated by Dialect.
 const TABLE_MIGRATIONS = "CREATE TABLE IF NOT EXISTS sqle_migrations(" +
 	"checksum varchar(32) NOT NULL," +
 	"module varchar(45) NOT NULL," +
@@ -31,14 +32,18 @@ const TABLE_MIGRATIONS = "CREATE TABLE IF NOT EXISTS sqle_migrations(" +
 	"migrated_on datetime NOT NULL," +
 	"execution_time varchar(25) NOT NULL," +
 	"scripts text NOT NULL," +
+	"tenant varchar(45) NOT NULL DEFAULT ''," +
 	"PRIMARY KEY (checksum));"
 
+// TABLE_ROTATIONS is the DDL of rotations table on MySQL/SQLite. Init creates it with DDL generated by Dialect.
 const TABLE_ROTATIONS = "CREATE TABLE IF NOT EXISTS sqle_rotations(" +
 	"checksum varchar(32) NOT NULL," +
-	"rotated_name varchar(8) NOT NULL," +
+	"rotated_name varchar(10) NOT NULL," +
 	"name varchar(45) NOT NULL," +
 	"rotated_on datetime NOT NULL," +
 	"execution_time varchar(25) NOT NULL," +
+	"pruned_on datetime NULL," +
+	"prune_action varchar(10) NULL," +
 	"PRIMARY KEY (checksum, rotated_name));"
 
 type MigrationStatus int
@@ -49,10 +54,42 @@ const (
 	MigrationStatusModified                        // already executed but checksum changed
 )
 
+func (s MigrationStatus) String() string {
+	switch s {
+	case MigrationStatusExecuted:
+		return "executed"
+	case MigrationStatusModified:
+		return "modified"
+	default:
+		return "new"
+	}
+}
+
 type Migrator struct {
-	dbs    []*sqle.DB
-	suffix string
-	module string
+	dbs     []*sqle.DB
+	suffix  string
+	module  string
+	dialect Dialect
+
+	lockOwner   string
+	lockTimeout time.Duration
+	lockTTL     time.Duration
+
+	concurrency     int
+	continueOnError bool
+
+	logger Logger
+
+	lookAhead int
+	backfill  int
+
+	modifiedPolicy ModifiedPolicy
+
+	tenants     []string
+	tenantQuery string
+	tenant      string // tenant that is migrated by a copy of Migrator
+
+	inputs map[string]string
 
 	Versions         []Semver
 	MonthlyRotations []Rotation
@@ -65,9 +102,10 @@ type Migrator struct {
 func (s *Migrator) Len() int      { return len(s.Versions) }
 func (s *Migrator) Swap(i, j int) { s.Versions[i], s.Versions[j] = s.Versions[j], s.Versions[i] }
 func (s *Migrator) Less(i, j int) bool {
-	l := s.Versions[i]
-	r := s.Versions[j]
+	return lessSemver(s.Versions[i], s.Versions[j])
+}
 
+func lessSemver(l, r Semver) bool {
 	if l.Major < r.Major {
 		return true
 	}
@@ -103,8 +141,18 @@ func (s *Migrator) Less(i, j int) bool {
 
 func New(dbs ...*sqle.DB) *Migrator {
 	return &Migrator{
-		dbs:      dbs,
-		suffix:   ".sql",
+		dbs:     dbs,
+		suffix:  ".sql",
+		dialect: MySQL,
+
+		lockOwner:   newLockOwner(),
+		lockTimeout: DefaultLockTimeout,
+		lockTTL:     DefaultLockTTL,
+
+		concurrency: 1,
+		logger:      boxLogger{},
+		lookAhead:   1,
+
 		Versions: make([]Semver, 0, 25),
 		now:      time.Now,
 	}
@@ -159,6 +207,12 @@ func (m *Migrator) Discover(fsys fs.FS, options ...Option) error {
 		return err
 	}
 
+	m.loadRegistry()
+	err = m.loadInputs()
+	if err != nil {
+		return err
+	}
+
 	sort.Sort(m)
 
 	return nil
@@ -178,6 +232,7 @@ func (m *Migrator) loadVersion(name string, major int, minor int, patch int, pre
 		return err
 	}
 
+	downs := make(map[string]string)
 	for _, di := range files {
 		if di.IsDir() {
 			continue
@@ -188,6 +243,15 @@ func (m *Migrator) loadVersion(name string, major int, minor int, patch int, pre
 			continue
 		}
 
+		if strings.HasSuffix(name, ".down"+m.suffix) {
+			buf, err := fs.ReadFile(fsys, filepath.Join(path, name))
+			if err != nil {
+				return err
+			}
+			downs[strings.TrimSuffix(name, ".down"+m.suffix)] = string(buf)
+			continue
+		}
+
 		mi, err := loadMigration(name, fsys, path)
 		if err != nil {
 			return err
@@ -196,6 +260,10 @@ func (m *Migrator) loadVersion(name string, major int, minor int, patch int, pre
 		v.Migrations = append(v.Migrations, mi)
 	}
 
+	for i, mi := range v.Migrations {
+		v.Migrations[i].DownScripts = downs[strings.TrimSuffix(mi.File, m.suffix)]
+	}
+
 	sort.Sort(&v)
 	m.Versions = append(m.Versions, v)
 	return nil
@@ -203,12 +271,34 @@ func (m *Migrator) loadVersion(name string, major int, minor int, patch int, pre
 
 func (m *Migrator) Init(ctx context.Context) error {
 	for _, db := range m.dbs {
-		_, err := db.ExecContext(ctx, TABLE_MIGRATIONS)
+		_, err := db.ExecContext(ctx, m.dialect.createTable("sqle_migrations", migrationsColumns, "checksum"))
+		if err != nil {
+			return err
+		}
+
+		// sqle_migrations that is created by previous releases has no tenant column
+		err = m.upgradeTable(ctx, db, "sqle_migrations", migrationsColumns)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.ExecContext(ctx, m.dialect.createTable("sqle_rotations", rotationsColumns, "checksum", "rotated_name"))
+		if err != nil {
+			return err
+		}
+
+		// sqle_rotations that is created by previous releases has no prune columns
+		err = m.upgradeTable(ctx, db, "sqle_rotations", rotationsColumns)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.ExecContext(ctx, m.dialect.createTable("sqle_migration_lock", lockColumns, "name"))
 		if err != nil {
 			return err
 		}
 
-		_, err = db.ExecContext(ctx, TABLE_ROTATIONS)
+		_, err = db.ExecContext(ctx, m.dialect.createTable("sqle_migration_progress", progressColumns, "checksum", "step"))
 		if err != nil {
 			return err
 		}
@@ -217,17 +307,20 @@ func (m *Migrator) Init(ctx context.Context) error {
 	return nil
 }
 
-func (m *Migrator) Migrate(ctx context.Context) error {
-	var err error
-	n := len(m.dbs)
-	for i, db := range m.dbs {
-		if n == 1 {
-			log.Printf("migrate: %s\n", m.module)
-		} else {
-			log.Printf("migrate db-%v: %s\n", i, m.module)
+// upgradeTable adds missing nullable or defaulted columns on an existing bookkeeping table.
+func (m *Migrator) upgradeTable(ctx context.Context, db *sqle.DB, table string, columns []column) error {
+	for _, c := range columns {
+		if !c.Null && c.Default == "" {
+			continue
+		}
+
+		rows, err := db.QueryContext(ctx, "SELECT "+m.dialect.quote(c.Name)+" FROM "+table+" WHERE 1 = 0")
+		if err == nil {
+			rows.Close()
+			continue
 		}
 
-		err = m.startMigrate(ctx, db)
+		_, err = db.ExecContext(ctx, m.dialect.addColumn(table, c))
 		if err != nil {
 			return err
 		}
@@ -236,97 +329,263 @@ func (m *Migrator) Migrate(ctx context.Context) error {
 	return nil
 }
 
-func (m *Migrator) startMigrate(ctx context.Context, db *sqle.DB) error {
+// hasTable checks if bookkeeping table exists on db. It is missing if Init has not been called on db yet.
+func (m *Migrator) hasTable(ctx context.Context, db *sqle.DB, table string) (bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT 1 FROM "+table+" WHERE 1 = 0")
+	if err == nil {
+		rows.Close()
+		return true, nil
+	}
+
+	// the query fails on a missing table only if db is reachable
+	if err = db.PingContext(ctx); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// Migrate migrates all discovered versions on all databases.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	_, err := m.MigrateWithReport(ctx)
+	return err
+}
+
+func (m *Migrator) startMigrate(ctx context.Context, db *sqle.DB, r *Report) error {
 	var err error
 
 	for _, v := range m.Versions {
 		n := len(v.Migrations)
-		w := len(strconv.Itoa(n))
-		log.Printf("┌─[ v%s ]\n", v.Name)
-		err = db.Transaction(ctx, nil, func(ctx context.Context, tx *sqle.Tx) error {
+		m.emit(ctx, Event{Type: EventVersionStarted, Action: "migrate", DB: r.DB, Version: v.Name})
 
-			for i, s := range v.Migrations {
-				status, err := m.getMigrationStatus(tx, v.Name, s)
+		// scripts are migrated in transactions, except scripts that have `/* tx: none */` header
+		for j := 0; j < n; {
+			if v.Migrations[j].NoTx {
+				err = m.migrateNoTx(ctx, db, r, v, j)
 				if err != nil {
 					return err
 				}
+				j++
+				continue
+			}
 
-				if status == MigrationStatusExecuted {
-					log.Printf("│ »[%*d/%d] %-35s %-10s [✔]", w, i+1, n, s.Name, "")
-					continue
-				}
+			k := j
+			for k < n && !v.Migrations[k].NoTx {
+				k++
+			}
 
-				if status == MigrationStatusModified {
-					log.Printf("│ »[%*d/%d] %-35s %-10s [!]", w, i+1, n, s.Name, "")
-					continue
-				}
+			err = m.migrateTx(ctx, db, r, v, j, k)
+			if err != nil {
+				return err
+			}
+			j = k
+		}
 
-				rotations := m.buildRotations(s.Rotate, s.RotateBegin, s.RotateEnd)
-
-				now := time.Now()
-				for _, it := range strings.Split(s.Scripts, ";") {
-					it := strings.TrimSpace(it)
-					if it != "" {
-						for _, rt := range rotations {
-							s := strings.ReplaceAll(it, "<rotate>", rt)
-							_, err = tx.Exec(s + ";")
-							if err != nil {
-								return err
-							}
-						}
-					}
-				}
+		m.emit(ctx, Event{Type: EventVersionCompleted, Action: "migrate", DB: r.DB, Version: v.Name})
 
-				cmd := sqle.New()
-				et := round(time.Since(now)).String()
-				cmd.Insert("sqle_migrations").
-					Set("checksum", s.Checksum).
-					Set("module", m.module).
-					Set("version", v.Name).
-					Set("name", s.Name).
-					Set("rank", s.Rank).
-					Set("scripts", s.Scripts).
-					Set("migrated_on", now).
-					Set("execution_time", et).
-					End()
+		err = m.refreshLock(ctx, db)
+		if err != nil {
+			return err
+		}
+	}
 
-				query, args, err := cmd.Build()
-				if err != nil {
-					return err
-				}
-				_, err = tx.ExecContext(ctx, query, args...)
-				if err != nil {
-					return err
-				}
+	return nil
+}
 
-				log.Printf("│ »[%*d/%d] %-35s %-10s [+]\n", w, i+1, n, s.Name, et)
-				if len(rotations) > 1 {
-					log.Printf("│ »      %-35s \n", "rotate:")
-					for _, rt := range rotations[1:] {
-						log.Printf("│ »       + %-35s \n", rt)
-					}
-				}
+// migrateTx migrates scripts from begin to end of version v in a transaction.
+func (m *Migrator) migrateTx(ctx context.Context, db *sqle.DB, r *Report, v Semver, begin, end int) error {
+	// scripts are reported after the transaction is committed
+	var applied, skipped []ScriptReport
+	var failed *ScriptReport
+	err := db.Transaction(ctx, nil, func(ctx context.Context, tx *sqle.Tx) error {
+		for j := begin; j < end; j++ {
+			sr, ok, err := m.applyMigration(ctx, tx, r.DB, v, j, func(s Migration, rotations []string) error {
+				return execMigration(ctx, tx, s, rotations)
+			})
+			if err != nil {
+				failed = &sr
+				return err
+			}
 
+			if ok {
+				applied = append(applied, sr)
+			} else {
+				skipped = append(skipped, sr)
 			}
+		}
+		return nil
+	})
 
-			log.Println("└────────────────────────────────────────────────────────────────")
-			return nil
-		})
+	if err != nil {
+		if failed != nil {
+			r.Failed = append(r.Failed, *failed)
+		}
+		return err
+	}
+
+	r.Applied = append(r.Applied, applied...)
+	r.Skipped = append(r.Skipped, skipped...)
+	return nil
+}
+
+// migrateNoTx migrates script j of version v without transaction. Executed statements are recorded in
+// sqle_migration_progress, so they are skipped when the script is resumed after a partial failure.
+func (m *Migrator) migrateNoTx(ctx context.Context, db *sqle.DB, r *Report, v Semver, j int) error {
+	sr, ok, err := m.applyMigration(ctx, db, r.DB, v, j, func(s Migration, rotations []string) error {
+		return m.execNoTx(ctx, db, s, rotations)
+	})
+	if err != nil {
+		r.Failed = append(r.Failed, sr)
+		return err
+	}
+
+	if !ok {
+		r.Skipped = append(r.Skipped, sr)
+		return nil
+	}
+
+	r.Applied = append(r.Applied, sr)
+	_, err = db.ExecBuilder(ctx, m.dialect.builder("DELETE FROM sqle_migration_progress WHERE checksum = {checksum}").
+		Param("checksum", v.Migrations[j].Checksum))
+	return err
+}
 
+// execNoTx executes statements of migration s that have not been executed one by one, and records its progress.
+func (m *Migrator) execNoTx(ctx context.Context, db *sqle.DB, s Migration, rotations []string) error {
+	var done int
+	err := db.QueryRowBuilder(ctx, m.dialect.builder("SELECT count(*) FROM sqle_migration_progress WHERE checksum = {checksum}").
+		Param("checksum", s.Checksum)).Scan(&done)
+	if err != nil {
+		return err
+	}
+
+	for k, it := range buildStatements(s.Scripts, rotations) {
+		if k < done {
+			continue
+		}
+
+		_, err = db.ExecContext(ctx, it.SQL)
 		if err != nil {
 			return err
 		}
 
+		cmd := m.dialect.builder()
+		cmd.Insert("sqle_migration_progress").
+			Set("checksum", s.Checksum).
+			Set("step", k).
+			Set("executed_on", time.Now()).
+			End()
+
+		_, err = db.ExecBuilder(ctx, cmd)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (m *Migrator) getMigrationStatus(tx *sqle.Tx, version string, s Migration) (MigrationStatus, error) {
+// applyMigration applies migration j of version v by exec, and records it in sqle_migrations with conn. A modified
+// migration is handled by ModifiedPolicy of Migrator. It returns true if the migration is executed.
+func (m *Migrator) applyMigration(ctx context.Context, conn sqle.Connector, i int, v Semver, j int, exec func(s Migration, rotations []string) error) (ScriptReport, bool, error) {
+	s := v.Migrations[j]
+	sr := ScriptReport{Tenant: m.tenant, Version: v.Name, Name: s.Name, Rank: s.Rank}
+	status, err := m.getMigrationStatus(ctx, conn, v.Name, s)
+	if err != nil {
+		sr.Err = err
+		return sr, false, err
+	}
+
+	sr.Status = status
+	e := Event{Action: "migrate", DB: i, Version: v.Name, Name: s.Name, Rank: s.Rank, Index: j + 1, Total: len(v.Migrations)}
+	if status == MigrationStatusExecuted {
+		e.Type = EventScriptExecuted
+		m.emit(ctx, e)
+		return sr, false, nil
+	}
+
+	if status == MigrationStatusModified {
+		switch m.modifiedPolicy {
+		case ModifiedFail:
+			e.Type = EventScriptModified
+			m.emit(ctx, e)
+			sr.Err = fmt.Errorf("%w: db-%v v%s %d_%s", ErrMigrationModified, i, v.Name, s.Rank, s.Name)
+			return sr, false, sr.Err
+		case ModifiedRerun:
+			// the record of previous script is replaced after it is executed again
+		default:
+			e.Type = EventScriptModified
+			m.emit(ctx, e)
+			return sr, false, nil
+		}
+	}
+
+	rotations := m.buildRotations(s.Rotate, s.RotateBegin, s.RotateEnd)
+
+	now := time.Now()
+	err = exec(s, rotations)
+	sr.Duration = time.Since(now)
+	if err != nil {
+		sr.Err = err
+		return sr, false, err
+	}
+
+	if status == MigrationStatusModified {
+		err = m.deleteMigration(ctx, conn, v.Name, s)
+		if err != nil {
+			sr.Err = err
+			return sr, false, err
+		}
+	}
+
+	err = m.insertMigration(ctx, conn, v.Name, s, now, sr.Duration)
+	if err != nil {
+		sr.Err = err
+		return sr, false, err
+	}
+
+	e.Type = EventScriptApplied
+	e.Rotations = rotations
+	e.Duration = sr.Duration
+	m.emit(ctx, e)
+	return sr, true, nil
+}
+
+// insertMigration records migration s of version in sqle_migrations.
+func (m *Migrator) insertMigration(ctx context.Context, conn sqle.Connector, version string, s Migration, migratedOn time.Time, d time.Duration) error {
+	cmd := m.dialect.builder()
+	cmd.Insert("sqle_migrations").
+		Set("checksum", s.Checksum).
+		Set("module", m.dialect.value(m.module)).
+		Set("tenant", m.dialect.value(m.tenant)).
+		Set("version", version).
+		Set("name", s.Name).
+		Set("rank", s.Rank).
+		Set("scripts", m.dialect.value(s.Scripts)).
+		Set("migrated_on", migratedOn).
+		Set("execution_time", round(d).String()).
+		End()
+
+	_, err := conn.ExecBuilder(ctx, cmd)
+	return err
+}
+
+// deleteMigration deletes the record of migration s of version from sqle_migrations.
+func (m *Migrator) deleteMigration(ctx context.Context, conn sqle.Connector, version string, s Migration) error {
+	_, err := conn.ExecBuilder(ctx, m.dialect.builder("DELETE FROM sqle_migrations WHERE module = {module} AND tenant = {tenant} AND version = {version} AND name = {name} AND "+m.dialect.quote("rank")+" = {rank}").
+		Param("module", m.dialect.value(m.module)).
+		Param("tenant", m.dialect.value(m.tenant)).
+		Param("version", version).
+		Param("name", s.Name).
+		Param("rank", s.Rank))
+	return err
+}
+
+func (m *Migrator) getMigrationStatus(ctx context.Context, conn sqle.Connector, version string, s Migration) (MigrationStatus, error) {
 	// First check if checksum already exists (most common case: script already executed)
 	var checksum string
-	err := tx.QueryRow("SELECT checksum FROM sqle_migrations WHERE checksum = ?",
-		s.Checksum).Scan(&checksum)
+	err := conn.QueryRowBuilder(ctx, m.dialect.builder("SELECT checksum FROM sqle_migrations WHERE checksum = {checksum}").
+		Param("checksum", s.Checksum)).Scan(&checksum)
 	if err == nil {
 		// Checksum exists, meaning a script with same content was already executed
 		return MigrationStatusExecuted, nil
@@ -336,8 +595,12 @@ func (m *Migrator) getMigrationStatus(tx *sqle.Tx, version string, s Migration)
 	}
 
 	// Checksum doesn't exist, check if a script with same name and rank was modified
-	err = tx.QueryRow("SELECT checksum FROM sqle_migrations WHERE module = ? AND version = ? AND name = ? AND rank = ?",
-		m.module, version, s.Name, s.Rank).Scan(&checksum)
+	err = conn.QueryRowBuilder(ctx, m.dialect.builder("SELECT checksum FROM sqle_migrations WHERE module = {module} AND tenant = {tenant} AND version = {version} AND name = {name} AND "+m.dialect.quote("rank")+" = {rank}").
+		Param("module", m.dialect.value(m.module)).
+		Param("tenant", m.dialect.value(m.tenant)).
+		Param("version", version).
+		Param("name", s.Name).
+		Param("rank", s.Rank)).Scan(&checksum)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// No record with same name and rank exists, it's a new script
@@ -350,6 +613,115 @@ func (m *Migrator) getMigrationStatus(tx *sqle.Tx, version string, s Migration)
 	return MigrationStatusModified, nil
 }
 
+// Status logs the status of all discovered scripts on all databases, and for every tenant if tenants are configured,
+// without executing them. It returns ErrMigrationModified if any executed script has been modified.
+func (m *Migrator) Status(ctx context.Context) error {
+	var modified bool
+	for i, db := range m.dbs {
+		m.emit(ctx, Event{Type: EventStarted, Action: "status", DB: i})
+
+		// all scripts are pending if db has not been initialized
+		initialized, err := m.hasTable(ctx, db, "sqle_migrations")
+		if err != nil {
+			return err
+		}
+
+		err = m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+			for _, v := range tm.Versions {
+				n := len(v.Migrations)
+				tm.emit(ctx, Event{Type: EventVersionStarted, Action: "status", DB: i, Version: v.Name})
+				for j, s := range v.Migrations {
+					status := MigrationStatusNew
+					if initialized {
+						var err error
+						status, err = tm.getMigrationStatus(ctx, db, v.Name, s)
+						if err != nil {
+							return err
+						}
+					}
+
+					e := Event{Action: "status", DB: i, Version: v.Name, Name: s.Name, Rank: s.Rank, Index: j + 1, Total: n}
+					switch status {
+					case MigrationStatusExecuted:
+						e.Type = EventScriptExecuted
+					case MigrationStatusModified:
+						modified = true
+						e.Type = EventScriptModified
+					default:
+						e.Type = EventScriptPending
+					}
+					tm.emit(ctx, e)
+				}
+				tm.emit(ctx, Event{Type: EventVersionCompleted, Action: "status", DB: i, Version: v.Name})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if modified {
+		return ErrMigrationModified
+	}
+
+	return nil
+}
+
+// Verify checks all discovered scripts on all databases, and for every tenant if tenants are configured. It returns
+// ErrMigrationModified with the first script whose content has been changed after it was executed.
+func (m *Migrator) Verify(ctx context.Context) error {
+	for i, db := range m.dbs {
+		// nothing has been executed if db has not been initialized
+		initialized, err := m.hasTable(ctx, db, "sqle_migrations")
+		if err != nil {
+			return err
+		}
+
+		if !initialized {
+			continue
+		}
+
+		err = m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+			for _, v := range tm.Versions {
+				for _, s := range v.Migrations {
+					status, err := tm.getMigrationStatus(ctx, db, v.Name, s)
+					if err != nil {
+						return err
+					}
+
+					if status == MigrationStatusModified {
+						if tm.tenant != "" {
+							return fmt.Errorf("%w: db-%v tenant-%s v%s %d_%s", ErrMigrationModified, i, tm.tenant, v.Name, s.Rank, s.Name)
+						}
+						return fmt.Errorf("%w: db-%v v%s %d_%s", ErrMigrationModified, i, v.Name, s.Rank, s.Name)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildStatements splits scripts into statements, and expands every statement on the table and all its rotated tables.
+func buildStatements(scripts string, rotations []string) []Statement {
+	var items []Statement
+	for _, it := range splitStatements(scripts) {
+		for _, rt := range rotations {
+			items = append(items, Statement{
+				Rotate: rt,
+				SQL:    strings.ReplaceAll(it, "<rotate>", rt) + ";",
+			})
+		}
+	}
+	return items
+}
+
 func (*Migrator) buildRotations(r shardid.TableRotate, begin, end time.Time) []string {
 	rotations := []string{""}
 	switch r {
@@ -370,97 +742,130 @@ func (*Migrator) buildRotations(r shardid.TableRotate, begin, end time.Time) []s
 	return rotations
 }
 
-func (m *Migrator) Rotate(ctx context.Context) error {
-	var err error
-	n := len(m.dbs)
-	for i, db := range m.dbs {
-		if n == 1 {
-			log.Printf("rotate: %s\n", m.module)
-		} else {
-			log.Printf("rotate db-%v: %s\n", i, m.module)
+// getRotatedNames returns rotated names from behind periods before now to ahead periods after now.
+func getRotatedNames(r shardid.TableRotate, now time.Time, ahead, behind int) []string {
+	var names []string
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := -behind; i <= ahead; i++ {
+		switch r {
+		case shardid.MonthlyRotate:
+			names = append(names, shardid.FormatMonth(month.AddDate(0, i, 0)))
+		case shardid.WeeklyRotate:
+			names = append(names, shardid.FormatWeek(now.AddDate(0, 0, 7*i)))
+		case shardid.DailyRotate:
+			names = append(names, shardid.FormatDay(now.AddDate(0, 0, i)))
+		default:
+			return nil
 		}
+	}
+	return names
+}
 
-		now := m.now().UTC()
-		months := []string{
-			"_" + now.Format("200601"),
-			"_" + now.AddDate(0, 1, 0).Format("200601"),
-		}
+// getRotatedNames returns rotated names that should be created by rotation r at now.
+func (m *Migrator) getRotatedNames(rt shardid.TableRotate, r Rotation, now time.Time) []string {
+	ahead, behind := m.lookAhead, m.backfill
+	if r.Ahead > 0 {
+		ahead = r.Ahead
+	}
 
-		err = startRotate(ctx, db, months, m.MonthlyRotations)
-		if err != nil {
-			return err
-		}
+	if r.Behind > 0 {
+		behind = r.Behind
+	}
 
-		var week int
-		_, week = now.ISOWeek() // 1-53 week
+	return getRotatedNames(rt, now, ahead, behind)
+}
 
-		next := now.AddDate(0, 0, 7)
-		_, nextWeek := next.ISOWeek()
+// getRotations returns discovered rotations grouped by their rotate types.
+func (m *Migrator) getRotations() []rotationGroup {
+	return []rotationGroup{
+		{Rotate: shardid.MonthlyRotate, Rotations: m.MonthlyRotations},
+		{Rotate: shardid.WeeklyRotate, Rotations: m.WeeklyRotations},
+		{Rotate: shardid.DailyRotate, Rotations: m.DailyRotations},
+	}
+}
 
-		weeks := []string{
-			"_" + now.Format("2006") + fmt.Sprintf("%03d", week),
-			"_" + next.Format("2006") + fmt.Sprintf("%03d", nextWeek),
-		}
+func (m *Migrator) Rotate(ctx context.Context) error {
+	var err error
+	for i, db := range m.dbs {
+		m.emit(ctx, Event{Type: EventStarted, Action: "rotate", DB: i})
+
+		now := m.now().UTC()
+		err = m.withLock(ctx, db, func(ctx context.Context) error {
+			for _, g := range m.getRotations() {
+				err := m.startRotate(ctx, i, db, g.Rotate, now, g.Rotations)
+				if err != nil {
+					return err
+				}
 
-		err = startRotate(ctx, db, weeks, m.WeeklyRotations)
+				err = m.refreshLock(ctx, db)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
 		if err != nil {
 			return err
 		}
+	}
 
-		days := []string{
-			"_" + now.Format("20060102"),
-			"_" + now.AddDate(0, 0, 1).Format("20060102"),
-		}
+	return nil
+}
 
-		err = startRotate(ctx, db, days, m.DailyRotations)
-		if err != nil {
-			return err
+// isRotated checks if the rotated table has been created by rotation r.
+func (m *Migrator) isRotated(ctx context.Context, conn sqle.Connector, r Rotation, rotatedName string) (bool, error) {
+	var checksum string
+	err := conn.QueryRowBuilder(ctx, m.dialect.builder("SELECT checksum FROM sqle_rotations WHERE checksum = {checksum} AND rotated_name = {rotated_name}").
+		Param("checksum", r.Checksum).
+		Param("rotated_name", rotatedName)).Scan(&checksum)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
 		}
-
+		return false, err
 	}
 
-	return nil
+	return true, nil
 }
 
-func startRotate(ctx context.Context, db *sqle.DB, rotatedNames []string, rotations []Rotation) error {
+func (m *Migrator) startRotate(ctx context.Context, i int, db *sqle.DB, rt shardid.TableRotate, now time.Time, rotations []Rotation) error {
 	var err error
-	var n int
-	var w int
-	var checksum string
 	for _, r := range rotations {
+		rotatedNames := m.getRotatedNames(rt, r, now)
+		n := len(rotatedNames)
 		err = db.Transaction(ctx, nil, func(ctx context.Context, tx *sqle.Tx) error {
-			n = len(rotatedNames)
-			w = len(strconv.Itoa(n))
-			log.Printf("┌─[ %s ]\n", r.Name)
+			m.emit(ctx, Event{Type: EventRotationStarted, Action: "rotate", DB: i, Name: r.Name})
 
-			for i, rn := range rotatedNames {
-				err = tx.QueryRow("SELECT checksum FROM sqle_rotations WHERE checksum = ? and rotated_name = ?", r.Checksum, rn).Scan(&checksum)
+			for j, rn := range rotatedNames {
+				rotated, err := m.isRotated(ctx, tx, r, rn)
 				if err != nil {
-					if !errors.Is(err, sql.ErrNoRows) {
-						return err
-					}
+					return err
 				}
 
-				if checksum != "" {
-					log.Printf("│ »[%*d/%d] %-35s %-10s [✔]", w, i+1, n, rn, "")
+				e := Event{Action: "rotate", DB: i, Name: r.Name, Index: j + 1, Total: n, Rotations: []string{rn}}
+				if rotated {
+					e.Type = EventRotationExists
+					m.emit(ctx, e)
 					continue
 				}
 
-				now := time.Now()
+				start := time.Now()
 
-				_, err = tx.Exec(strings.ReplaceAll(r.Script, "<rotate>", rn) + ";")
-				if err != nil {
-					return err
+				for _, it := range buildStatements(r.Script, []string{rn}) {
+					_, err = tx.ExecContext(ctx, it.SQL)
+					if err != nil {
+						return err
+					}
 				}
 
-				cmd := sqle.New()
-				et := round(time.Since(now)).String()
+				cmd := m.dialect.builder()
+				e.Duration = time.Since(start)
 				cmd.Insert("sqle_rotations").
 					Set("checksum", r.Checksum).
 					Set("name", r.Name).
 					Set("rotated_name", rn).
-					Set("rotated_on", now).
-					Set("execution_time", et).
+					Set("rotated_on", start).
+					Set("execution_time", round(e.Duration).String()).
 					End()
 
 				query, args, err := cmd.Build()
@@ -472,10 +877,11 @@ func startRotate(ctx context.Context, db *sqle.DB, rotatedNames []string, rotati
 					return err
 				}
 
-				log.Printf("│ »[%*d/%d] %-35s %-10s [+]\n", w, i+1, n, rn, et)
+				e.Type = EventRotationCreated
+				m.emit(ctx, e)
 			}
 
-			log.Println("└────────────────────────────────────────────────────────────────")
+			m.emit(ctx, Event{Type: EventRotationCompleted, Action: "rotate", DB: i, Name: r.Name})
 			return nil
 		})
 
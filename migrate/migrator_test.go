@@ -705,6 +705,59 @@ func TestRotate(t *testing.T) {
 
 			},
 		},
+		{
+			name: "look_ahead_and_backfill_should_work",
+			setup: func(db *sql.DB) (*Migrator, error) {
+
+				m := New(sqle.Open(db))
+				m.now = func() time.Time {
+					return time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+				}
+
+				err := m.Discover(fstest.MapFS{
+					"daily/daily_logs.sql": &fstest.MapFile{
+						Data: []byte(`CREATE TABLE IF NOT EXISTS daily_logs<rotate> (
+							id int NOT NULL,
+							PRIMARY KEY (id)
+						);`),
+					},
+					"monthly/monthly_logs.sql": &fstest.MapFile{
+						Data: []byte(`/* ahead: 2 */
+						/* behind: 1 */
+						CREATE TABLE IF NOT EXISTS monthly_logs<rotate> (
+							id int NOT NULL,
+							PRIMARY KEY (id)
+						);`),
+					},
+				}, WithLookAhead(3), WithBackfill(2))
+
+				if err != nil {
+					return nil, err
+				}
+
+				return m, nil
+
+			},
+			assert: func(t *testing.T, m *Migrator) {
+				var id int64
+
+				rotations := []string{
+					"daily_logs_20240130", "daily_logs_20240131", "daily_logs_20240201", "daily_logs_20240202", "daily_logs_20240203", "daily_logs_20240204",
+					"monthly_logs_202401", "monthly_logs_202402", "monthly_logs_202403", "monthly_logs_202404",
+				}
+
+				for _, rt := range rotations {
+					err := m.dbs[0].QueryRow("SELECT id FROM "+rt+" WHERE id=?", 0).Scan(&id)
+					require.ErrorIs(t, err, sql.ErrNoRows)
+				}
+
+				for _, rt := range []string{"daily_logs_20240129", "daily_logs_20240205", "monthly_logs_202312", "monthly_logs_202405"} {
+					err := m.dbs[0].QueryRow("SELECT id FROM "+rt+" WHERE id=?", 0).Scan(&id)
+					require.ErrorContains(t, err, "no such table")
+				}
+
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -728,3 +781,96 @@ func TestRotate(t *testing.T) {
 	}
 
 }
+
+func TestVerify(t *testing.T) {
+	db, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+
+	m := New(sqle.Open(db))
+	err = m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}, WithModule("tests"))
+	require.NoError(t, err)
+
+	err = m.Init(context.TODO())
+	require.NoError(t, err)
+
+	err = m.Status(context.TODO())
+	require.NoError(t, err)
+
+	err = m.Migrate(context.TODO())
+	require.NoError(t, err)
+
+	err = m.Verify(context.TODO())
+	require.NoError(t, err)
+
+	m = New(sqle.Open(db))
+	err = m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, name varchar(45), PRIMARY KEY (id));`),
+		},
+	}, WithModule("tests"))
+	require.NoError(t, err)
+
+	err = m.Verify(context.TODO())
+	require.ErrorIs(t, err, ErrMigrationModified)
+
+	err = m.Status(context.TODO())
+	require.ErrorIs(t, err, ErrMigrationModified)
+}
+
+func TestMigrateNoTx(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/2_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`/* tx: none */
+CREATE TABLE users (id int NOT NULL, PRIMARY KEY (id));
+INSERT INTO members (id) SELECT id FROM users;`),
+		},
+		"0.1.0/3_create_table_logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE logs (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.True(t, m.Versions[0].Migrations[1].NoTx)
+	require.NoError(t, m.Init(context.TODO()))
+
+	reports, err := m.MigrateWithReport(context.TODO())
+	require.Error(t, err)
+	require.Len(t, reports[0].Applied, 1)
+	require.Len(t, reports[0].Failed, 1)
+	require.Equal(t, "create_table_users", reports[0].Failed[0].Name)
+
+	// scripts before it are committed, and its executed statements are recorded
+	var n int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migrations").Scan(&n))
+	require.Equal(t, 1, n)
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migration_progress").Scan(&n))
+	require.Equal(t, 1, n)
+
+	_, err = db.Exec("CREATE TABLE members (id int NOT NULL, PRIMARY KEY (id))")
+	require.NoError(t, err)
+
+	// executed statements are skipped on resume, otherwise CREATE TABLE users fails
+	reports, err = m.MigrateWithReport(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, reports[0].Applied, 2)
+	require.Len(t, reports[0].Skipped, 1)
+
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migrations").Scan(&n))
+	require.Equal(t, 3, n)
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migration_progress").Scan(&n))
+	require.Equal(t, 0, n)
+}
@@ -1,6 +1,9 @@
 package migrate
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 type Option func(m *Migrator)
 
@@ -21,3 +24,101 @@ func WithModule(name string) Option {
 		m.module = name
 	}
 }
+
+// WithDialect sets the dialect of bookkeeping tables and statements. MySQL is used by default.
+func WithDialect(d Dialect) Option {
+	return func(m *Migrator) {
+		m.dialect = d
+	}
+}
+
+// WithLockTimeout sets the duration to wait for the migration lock on each database. DefaultLockTimeout is used by default.
+func WithLockTimeout(d time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = d
+	}
+}
+
+// WithLockTTL sets the duration before a migration lock is considered stale and can be taken over by others.
+// It is refreshed after each version is migrated. DefaultLockTTL is used by default.
+func WithLockTTL(d time.Duration) Option {
+	return func(m *Migrator) {
+		if d > 0 {
+			m.lockTTL = d
+		}
+	}
+}
+
+// WithConcurrency sets the maximum number of databases that are migrated concurrently. It is 1 by default.
+func WithConcurrency(n int) Option {
+	return func(m *Migrator) {
+		if n > 0 {
+			m.concurrency = n
+		}
+	}
+}
+
+// WithContinueOnError keeps migrating other databases when a database fails.
+func WithContinueOnError() Option {
+	return func(m *Migrator) {
+		m.continueOnError = true
+	}
+}
+
+// WithLogger sets the Logger that receives events of Migrator. Events are written as box-drawing tables by standard logger by default.
+func WithLogger(l Logger) Option {
+	return func(m *Migrator) {
+		if l != nil {
+			m.logger = l
+		}
+	}
+}
+
+// WithLookAhead sets the number of periods after current period that rotated tables are created for by Rotate.
+// It is 1 by default, and can be overridden by `/* ahead: n */` header in rotation file.
+func WithLookAhead(n int) Option {
+	return func(m *Migrator) {
+		if n >= 0 {
+			m.lookAhead = n
+		}
+	}
+}
+
+// WithBackfill sets the number of periods before current period that rotated tables are created for by Rotate.
+// It is 0 by default, and can be overridden by `/* behind: n */` header in rotation file.
+func WithBackfill(n int) Option {
+	return func(m *Migrator) {
+		if n >= 0 {
+			m.backfill = n
+		}
+	}
+}
+
+// WithModifiedPolicy sets how Migrate handles a script that has been modified after it was executed. It is ModifiedWarn by default.
+func WithModifiedPolicy(p ModifiedPolicy) Option {
+	return func(m *Migrator) {
+		m.modifiedPolicy = p
+	}
+}
+
+// WithTenants sets tenants that all versions are migrated for by Migrate. <tenant> in scripts is replaced with tenant name.
+func WithTenants(names ...string) Option {
+	return func(m *Migrator) {
+		m.tenants = names
+	}
+}
+
+// WithTenantQuery sets the query that discovers tenants on each database for Migrate. It should return tenant names in first column.
+func WithTenantQuery(query string) Option {
+	return func(m *Migrator) {
+		m.tenantQuery = query
+	}
+}
+
+// WithInputs sets inputs that replace <name> tokens in scripts and rotations. eg <engine> or <charset>.
+// <rotate> and <tenant> are reserved, and Discover fails if a script references an undefined input.
+func WithInputs(inputs map[string]string) Option {
+	return func(m *Migrator) {
+		m.inputs = inputs
+	}
+}
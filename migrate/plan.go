@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+)
+
+// Step is a script or a rotation that is planned to be applied on a database.
+type Step struct {
+	DB      int    // index of database in Migrator
+	Tenant  string // tenant that script is planned for, it is empty if tenants are not configured
+	Version string // version of script, it is empty for rotation
+	Name    string // name of script or rotation
+	Rank    int
+	Status  MigrationStatus
+
+	// Statements will be executed on the table and its rotated tables. It is empty if nothing will be executed.
+	Statements []Statement
+}
+
+// Statement is a sql statement that is expanded on a table or its rotated table.
+type Statement struct {
+	Rotate string // rotated table suffix, it is empty for the table itself
+	SQL    string
+}
+
+// Plan returns the steps that Migrate would apply on all databases, and for every tenant if tenants are configured,
+// without executing anything. Bookkeeping tables are not required, all scripts are new on a database that has not been
+// initialized. Modified scripts are handled by ModifiedPolicy as Migrate does, their statements are planned by
+// ModifiedRerun, and ErrMigrationModified is returned by ModifiedFail.
+func (m *Migrator) Plan(ctx context.Context) ([]Step, error) {
+	var steps []Step
+	for i, db := range m.dbs {
+		// all scripts are new if db has not been initialized
+		initialized, err := m.hasTable(ctx, db, "sqle_migrations")
+		if err != nil {
+			return nil, err
+		}
+
+		err = m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+			for _, v := range tm.Versions {
+				for _, s := range v.Migrations {
+					status := MigrationStatusNew
+					if initialized {
+						var err error
+						status, err = tm.getMigrationStatus(ctx, db, v.Name, s)
+						if err != nil {
+							return err
+						}
+					}
+
+					step := Step{
+						DB:      i,
+						Tenant:  tm.tenant,
+						Version: v.Name,
+						Name:    s.Name,
+						Rank:    s.Rank,
+						Status:  status,
+					}
+
+					if status == MigrationStatusModified && tm.modifiedPolicy == ModifiedFail {
+						if tm.tenant != "" {
+							return fmt.Errorf("%w: db-%v tenant-%s v%s %d_%s", ErrMigrationModified, i, tm.tenant, v.Name, s.Rank, s.Name)
+						}
+						return fmt.Errorf("%w: db-%v v%s %d_%s", ErrMigrationModified, i, v.Name, s.Rank, s.Name)
+					}
+
+					// a modified script is executed again by ModifiedRerun
+					if status == MigrationStatusNew || (status == MigrationStatusModified && tm.modifiedPolicy == ModifiedRerun) {
+						step.Statements = buildStatements(s.Scripts, tm.buildRotations(s.Rotate, s.RotateBegin, s.RotateEnd))
+					}
+
+					steps = append(steps, step)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return steps, nil
+}
+
+// PlanRotate returns the steps that Rotate would apply on all databases without executing anything. Bookkeeping tables
+// are not required, all rotated tables are new on a database that has not been initialized.
+func (m *Migrator) PlanRotate(ctx context.Context) ([]Step, error) {
+	var steps []Step
+	now := m.now().UTC()
+	for i, db := range m.dbs {
+		// all rotated tables are new if db has not been initialized
+		initialized, err := m.hasTable(ctx, db, "sqle_rotations")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range m.getRotations() {
+			for _, r := range g.Rotations {
+				rotatedNames := m.getRotatedNames(g.Rotate, r, now)
+				step := Step{
+					DB:     i,
+					Name:   r.Name,
+					Status: MigrationStatusExecuted,
+				}
+
+				for _, rn := range rotatedNames {
+					rotated := false
+					if initialized {
+						rotated, err = m.isRotated(ctx, db, r, rn)
+						if err != nil {
+							return nil, err
+						}
+					}
+
+					if !rotated {
+						step.Status = MigrationStatusNew
+						step.Statements = append(step.Statements, buildStatements(r.Script, []string{rn})...)
+					}
+				}
+
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestPlan(t *testing.T) {
+	d0, clean0, err := createSqlite3()
+	defer clean0()
+	require.NoError(t, err)
+
+	d1, clean1, err := createSqlite3()
+	defer clean1()
+	require.NoError(t, err)
+
+	db0 := sqle.Open(d0)
+	db1 := sqle.Open(d1)
+
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/2_create_monthly_logs.sql": &fstest.MapFile{
+			Data: []byte(`/* rotate: monthly = 20240201 - 20240301 */
+CREATE TABLE IF NOT EXISTS monthly_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"daily/daily_logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS daily_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	m := New(db0)
+	m.now = func() time.Time {
+		return time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	}
+	require.NoError(t, m.Discover(fsys))
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+
+	// db-1 has not been initialized
+	m.dbs = append(m.dbs, db1)
+
+	steps, err := m.Plan(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, steps, 4)
+
+	// db-0 has been migrated
+	require.Equal(t, 0, steps[0].DB)
+	require.Equal(t, "0.1.0", steps[0].Version)
+	require.Equal(t, "create_table_roles", steps[0].Name)
+	require.Equal(t, MigrationStatusExecuted, steps[0].Status)
+	require.Empty(t, steps[0].Statements)
+	require.Equal(t, MigrationStatusExecuted, steps[1].Status)
+	require.Empty(t, steps[1].Statements)
+
+	// db-1 is new
+	require.Equal(t, 1, steps[2].DB)
+	require.Equal(t, MigrationStatusNew, steps[2].Status)
+	require.Equal(t, []Statement{
+		{SQL: "CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));"},
+	}, steps[2].Statements)
+
+	require.Equal(t, 1, steps[3].DB)
+	require.Equal(t, 2, steps[3].Rank)
+	require.Equal(t, MigrationStatusNew, steps[3].Status)
+	require.Equal(t, []Statement{
+		{Rotate: "", SQL: "/* rotate: monthly = 20240201 - 20240301 */\nCREATE TABLE IF NOT EXISTS monthly_logs (id int NOT NULL, PRIMARY KEY (id));"},
+		{Rotate: "_202402", SQL: "/* rotate: monthly = 20240201 - 20240301 */\nCREATE TABLE IF NOT EXISTS monthly_logs_202402 (id int NOT NULL, PRIMARY KEY (id));"},
+		{Rotate: "_202403", SQL: "/* rotate: monthly = 20240201 - 20240301 */\nCREATE TABLE IF NOT EXISTS monthly_logs_202403 (id int NOT NULL, PRIMARY KEY (id));"},
+	}, steps[3].Statements)
+
+	// nothing is executed on db-1
+	var n int
+	require.NoError(t, db1.QueryRow("SELECT count(*) FROM sqlite_master WHERE name LIKE 'sqle_%'").Scan(&n))
+	require.Equal(t, 0, n)
+
+	require.NoError(t, m.dbs[0].QueryRow("SELECT count(*) FROM sqle_rotations").Scan(&n))
+	require.Equal(t, 0, n)
+
+	steps, err = m.PlanRotate(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	require.Equal(t, Step{
+		DB:     0,
+		Name:   "daily_logs",
+		Status: MigrationStatusNew,
+		Statements: []Statement{
+			{Rotate: "_20240201", SQL: "CREATE TABLE IF NOT EXISTS daily_logs_20240201 (id int NOT NULL, PRIMARY KEY (id));"},
+			{Rotate: "_20240202", SQL: "CREATE TABLE IF NOT EXISTS daily_logs_20240202 (id int NOT NULL, PRIMARY KEY (id));"},
+		},
+	}, steps[0])
+	require.Equal(t, 1, steps[1].DB)
+	require.Equal(t, MigrationStatusNew, steps[1].Status)
+	require.Len(t, steps[1].Statements, 2)
+
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Rotate(context.TODO()))
+
+	steps, err = m.PlanRotate(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	require.Equal(t, MigrationStatusExecuted, steps[0].Status)
+	require.Empty(t, steps[0].Statements)
+	require.Equal(t, MigrationStatusExecuted, steps[1].Status)
+}
+
+func TestPlanModified(t *testing.T) {
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+
+	// script is modified after it was executed
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, name varchar(45), PRIMARY KEY (id));`),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		policy     ModifiedPolicy
+		statements []Statement
+		err        error
+	}{
+		{name: "warn_should_skip", policy: ModifiedWarn},
+		{
+			name:       "rerun_should_plan_statements",
+			policy:     ModifiedRerun,
+			statements: []Statement{{SQL: "CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, name varchar(45), PRIMARY KEY (id));"}},
+		},
+		{name: "fail_should_fail", policy: ModifiedFail, err: ErrMigrationModified},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := New(db)
+			require.NoError(t, m.Discover(fsys, WithModule("tests"), WithModifiedPolicy(test.policy), WithLogger(DiscardLogger)))
+
+			steps, err := m.Plan(context.TODO())
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, steps, 1)
+			require.Equal(t, MigrationStatusModified, steps[0].Status)
+			require.Equal(t, test.statements, steps[0].Statements)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package migrate
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yaitoo/sqle"
+	"github.com/yaitoo/sqle/shardid"
+)
+
+var regexpRotatedTable = regexp.MustCompile("(?i)CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?[`\"]?([\\w.]+)<rotate>")
+
+// Prune drops expired rotated tables of rotations that have a retain header on all databases, or renames them to
+// `{table}{rotate}_archive` if the rotation has `/* prune: archive */` header. A rotated table is expired if all
+// its period is earlier than the retention window. Pruned rotated tables are marked in sqle_rotations, so they are
+// neither pruned nor created again.
+func (m *Migrator) Prune(ctx context.Context) error {
+	var err error
+	for i, db := range m.dbs {
+		m.emit(ctx, Event{Type: EventStarted, Action: "prune", DB: i})
+
+		now := m.now().UTC()
+		err = m.withLock(ctx, db, func(ctx context.Context) error {
+			for _, g := range m.getRotations() {
+				for _, r := range g.Rotations {
+					if r.Retain == 0 {
+						continue
+					}
+
+					err := m.startPrune(ctx, i, db, g.Rotate, r, now)
+					if err != nil {
+						return err
+					}
+
+					err = m.refreshLock(ctx, db)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getExpiredNames returns rotated names of rotation r that are expired at now, and have not been pruned. They are
+// sorted by start time of their periods.
+func (m *Migrator) getExpiredNames(ctx context.Context, conn sqle.Connector, rt shardid.TableRotate, r Rotation, now time.Time) ([]string, error) {
+	// begin is the start time of the period that contains retain time
+	t := r.getRetainTime(now)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	var begin time.Time
+	switch rt {
+	case shardid.MonthlyRotate:
+		begin = day.AddDate(0, 0, 1-day.Day())
+	case shardid.WeeklyRotate:
+		begin = getISOWeekStart(t.ISOWeek())
+	case shardid.DailyRotate:
+		begin = day
+	default:
+		return nil, nil
+	}
+
+	rows, err := conn.QueryBuilder(ctx, m.dialect.builder("SELECT DISTINCT rotated_name FROM sqle_rotations WHERE name = {name} AND pruned_on IS NULL ORDER BY rotated_name").
+		Param("name", r.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		names  []string
+		starts = make(map[string]time.Time)
+	)
+	for rows.Next() {
+		var rn string
+		err = rows.Scan(&rn)
+		if err != nil {
+			return nil, err
+		}
+
+		// names are compared by their periods instead of strings, a name that can't be parsed is never pruned
+		start, ok := parseRotatedName(rt, rn)
+		if ok && start.Before(begin) {
+			names = append(names, rn)
+			starts[rn] = start
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return starts[names[i]].Before(starts[names[j]])
+	})
+
+	return names, nil
+}
+
+// parseRotatedName returns the start time of the period of rotated name rn. eg `_202402` is 2024-02-01. Weekly names
+// are formatted by calendar year and ISO week, so a name is shared by two weeks at the turn of a year, eg `_2024001` is
+// both the week of 2024-01-01 and the week of 2024-12-30. The later one is returned, so a table is never pruned before
+// all its data is expired.
+func parseRotatedName(rt shardid.TableRotate, rn string) (time.Time, bool) {
+	switch rt {
+	case shardid.MonthlyRotate:
+		t, err := time.Parse("_200601", rn)
+		return t, err == nil
+	case shardid.WeeklyRotate:
+		if len(rn) != 8 || rn[0] != '_' {
+			return time.Time{}, false
+		}
+
+		year, err := strconv.Atoi(rn[1:5])
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		week, err := strconv.Atoi(rn[5:])
+		if err != nil || week < 1 || week > 53 {
+			return time.Time{}, false
+		}
+
+		if week == 1 {
+			// last days of year are in the first ISO week of next year
+			if next := getISOWeekStart(year+1, 1); next.Year() == year {
+				return next, true
+			}
+			return getISOWeekStart(year, 1), true
+		}
+
+		start := getISOWeekStart(year, week)
+		if y, w := start.ISOWeek(); y != year || w != week {
+			// first days of year are in the last ISO week of previous year
+			start = getISOWeekStart(year-1, week)
+		}
+		return start, true
+	case shardid.DailyRotate:
+		t, err := time.Parse("_20060102", rn)
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// getISOWeekStart returns Monday of the ISO week of year.
+func getISOWeekStart(year, week int) time.Time {
+	// January 4th is always in the first ISO week
+	jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, 7*(week-1))
+}
+
+// getRotatedTables returns tables that are created with <rotate> in script.
+func getRotatedTables(script string) []string {
+	var tables []string
+	for _, it := range regexpRotatedTable.FindAllStringSubmatch(script, -1) {
+		tables = append(tables, it[1])
+	}
+	return tables
+}
+
+func (m *Migrator) startPrune(ctx context.Context, i int, db *sqle.DB, rt shardid.TableRotate, r Rotation, now time.Time) error {
+	names, err := m.getExpiredNames(ctx, db, rt, r, now)
+	if err != nil {
+		return err
+	}
+
+	tables := getRotatedTables(r.Script)
+	action := "drop"
+	if r.Archive {
+		action = "archive"
+	}
+
+	m.emit(ctx, Event{Type: EventRotationStarted, Action: "prune", DB: i, Name: r.Name})
+	n := len(names)
+	for j, rn := range names {
+		err = db.Transaction(ctx, nil, func(ctx context.Context, tx *sqle.Tx) error {
+			e := Event{Action: "prune", DB: i, Name: r.Name, Index: j + 1, Total: n}
+			start := time.Now()
+			for _, t := range tables {
+				var cmd string
+				if r.Archive {
+					// new name of ALTER TABLE RENAME can't be qualified by schema
+					archived := t[strings.LastIndex(t, ".")+1:] + rn + "_archive"
+					cmd = "ALTER TABLE " + t + rn + " RENAME TO " + archived
+					e.Rotations = append(e.Rotations, archived)
+				} else {
+					cmd = "DROP TABLE IF EXISTS " + t + rn
+					e.Rotations = append(e.Rotations, t+rn)
+				}
+
+				_, err := tx.ExecContext(ctx, cmd)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.ExecBuilder(ctx, m.dialect.builder("UPDATE sqle_rotations SET pruned_on = {now}, prune_action = {action} WHERE name = {name} AND rotated_name = {rotated_name}").
+				Param("now", now).
+				Param("action", action).
+				Param("name", r.Name).
+				Param("rotated_name", rn))
+			if err != nil {
+				return err
+			}
+
+			e.Type = EventRotationDropped
+			if r.Archive {
+				e.Type = EventRotationArchived
+			}
+			e.Duration = time.Since(start)
+			m.emit(ctx, e)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	m.emit(ctx, Event{Type: EventRotationCompleted, Action: "prune", DB: i, Name: r.Name})
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+	"github.com/yaitoo/sqle/shardid"
+)
+
+func TestPrune(t *testing.T) {
+	fsys := fstest.MapFS{
+		"monthly/monthly_logs.sql": &fstest.MapFile{
+			Data: []byte("/* retain: 2 months */\nCREATE TABLE IF NOT EXISTS monthly_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));"),
+		},
+		"daily/daily_logs.sql": &fstest.MapFile{
+			Data: []byte("/* retain: 1 day */\n/* prune: archive */\nCREATE TABLE IF NOT EXISTS daily_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));"),
+		},
+		"weekly/weekly_logs.sql": &fstest.MapFile{
+			Data: []byte("CREATE TABLE IF NOT EXISTS weekly_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));"),
+		},
+	}
+
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+
+	for _, day := range []int{-30, 1, 20, 40, 60, 80} {
+		m.now = func() time.Time {
+			return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+		}
+		require.NoError(t, m.Rotate(context.TODO()))
+	}
+
+	// now is 2024-03-20
+	require.NoError(t, m.Prune(context.TODO()))
+
+	tableExists := func(name string) bool {
+		var n int
+		err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+		require.NoError(t, err)
+		return n > 0
+	}
+
+	// data from 2024-01-20 is retained
+	require.False(t, tableExists("monthly_logs_202312"))
+	require.True(t, tableExists("monthly_logs_202401"))
+	require.True(t, tableExists("monthly_logs_202404"))
+
+	// data from 2024-03-19 is retained, and expired tables are archived
+	require.False(t, tableExists("daily_logs_20240101"))
+	require.True(t, tableExists("daily_logs_20240101_archive"))
+	require.True(t, tableExists("daily_logs_20240301_archive"))
+	require.True(t, tableExists("daily_logs_20240320"))
+
+	// no retention
+	require.True(t, tableExists("weekly_logs_2024001"))
+
+	var action string
+	err = db.QueryRow("SELECT prune_action FROM sqle_rotations WHERE name = 'daily_logs' AND rotated_name = '_20240101'").Scan(&action)
+	require.NoError(t, err)
+	require.Equal(t, "archive", action)
+
+	err = db.QueryRow("SELECT prune_action FROM sqle_rotations WHERE name = 'monthly_logs' AND rotated_name = '_202312'").Scan(&action)
+	require.NoError(t, err)
+	require.Equal(t, "drop", action)
+
+	// pruned tables are neither pruned again nor created by rotate
+	require.NoError(t, m.Prune(context.TODO()))
+	m.now = func() time.Time {
+		return time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	}
+	require.NoError(t, m.Rotate(context.TODO()))
+	require.False(t, tableExists("monthly_logs_202312"))
+}
+
+func TestPruneUpgrade(t *testing.T) {
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	// sqle_rotations is created by previous release
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS sqle_rotations(checksum varchar(32) NOT NULL,rotated_name varchar(10) NOT NULL,name varchar(45) NOT NULL,rotated_on datetime NOT NULL,execution_time varchar(25) NOT NULL,PRIMARY KEY (checksum, rotated_name))")
+	require.NoError(t, err)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{}, WithModule("tests")))
+	require.NoError(t, m.Init(context.TODO()))
+	// it is safe to init again
+	require.NoError(t, m.Init(context.TODO()))
+
+	rows, err := db.Query("SELECT pruned_on, prune_action FROM sqle_rotations")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+}
+
+func TestPruneWeekly(t *testing.T) {
+	fsys := fstest.MapFS{
+		"weekly/weekly_logs.sql": &fstest.MapFile{
+			Data: []byte("/* retain: 1 week */\nCREATE TABLE IF NOT EXISTS weekly_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));"),
+		},
+	}
+
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+
+	tableExists := func(name string) bool {
+		var n int
+		err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+		require.NoError(t, err)
+		return n > 0
+	}
+
+	rotate := func(day int) {
+		m.now = func() time.Time {
+			return time.Date(2024, 12, day, 0, 0, 0, 0, time.UTC)
+		}
+		require.NoError(t, m.Rotate(context.TODO()))
+	}
+
+	rotate(16)
+	rotate(30)
+
+	// now is 2024-12-30, data from 2024-12-23 is retained
+	require.NoError(t, m.Prune(context.TODO()))
+	require.False(t, tableExists("weekly_logs_2024051"))
+	require.True(t, tableExists("weekly_logs_2024052"))
+	// 2024-12-30 is in the first ISO week, which shares its name with the week of 2024-01-01
+	require.True(t, tableExists("weekly_logs_2024001"))
+
+	// now is 2025-01-13, data from 2025-01-06 is retained
+	rotate(44)
+	require.NoError(t, m.Prune(context.TODO()))
+	require.False(t, tableExists("weekly_logs_2024052"))
+	require.False(t, tableExists("weekly_logs_2024001"))
+	require.True(t, tableExists("weekly_logs_2025002"))
+	require.True(t, tableExists("weekly_logs_2025003"))
+}
+
+func TestParseRotatedName(t *testing.T) {
+	tests := []struct {
+		rt   shardid.TableRotate
+		name string
+		want time.Time
+		ok   bool
+	}{
+		{rt: shardid.MonthlyRotate, name: "_202402", want: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.WeeklyRotate, name: "_2024010", want: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.WeeklyRotate, name: "_2024001", want: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.WeeklyRotate, name: "_2026001", want: time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.WeeklyRotate, name: "_2020053", want: time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.WeeklyRotate, name: "_2021053", want: time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.WeeklyRotate, name: "_2022052", want: time.Date(2022, 12, 26, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.WeeklyRotate, name: "_2024054"},
+		{rt: shardid.DailyRotate, name: "_20240229", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), ok: true},
+		{rt: shardid.DailyRotate, name: "_archive"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := parseRotatedName(test.rt, test.name)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package migrate
+
+import (
+	"context"
+	// skipcq: GSC-G501
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yaitoo/sqle"
+	"github.com/yaitoo/sqle/shardid"
+)
+
+// MigrateFunc is a Go migration. It is executed in the transaction of its version, once for the table and once
+// for each rotated table. Use RotatedName to get current rotated table suffix.
+type MigrateFunc func(ctx context.Context, tx *sqle.Tx) error
+
+type registeredMigration struct {
+	Version   string
+	Migration Migration
+}
+
+var (
+	registryMu sync.Mutex
+	registry   map[string][]registeredMigration // module => its Go migrations
+)
+
+type rotatedNameKey struct{}
+
+// RotatedName returns the rotated table suffix that a Go migration is executed on. eg `_202402`.
+// It is empty for the table itself.
+func RotatedName(ctx context.Context) string {
+	rn, _ := ctx.Value(rotatedNameKey{}).(string)
+	return rn
+}
+
+// Register registers a Go migration of module that is ordered by rank together with sql files in version. It is merged
+// into discovered versions of every Migrator with the same module, and should be called in init functions. It panics
+// if version is invalid, or a migration with same module, version, rank and name has been registered.
+func Register(module, version string, rank int, name string, fn MigrateFunc) {
+	RegisterRotate(module, version, rank, name, "", fn)
+}
+
+// RegisterRotate registers a Go migration like Register, and it is executed on the table and its rotated tables in
+// rotate range too. rotate has same format as rotate header of sql files. eg `monthly=20240201-20240401`.
+func RegisterRotate(module, version string, rank int, name string, rotate string, fn MigrateFunc) {
+	if fn == nil {
+		panic("migrate: Register func is nil")
+	}
+
+	if !regexpSemver.MatchString(version) {
+		panic("migrate: Register invalid version " + version)
+	}
+
+	mi := Migration{
+		Name: name,
+		Rank: rank,
+		Func: fn,
+	}
+
+	if rotate != "" {
+		options := strings.Split(strings.ReplaceAll(rotate, " ", ""), "=")
+		if len(options) != 2 || getRotate(options[0]) == shardid.NoRotate {
+			panic("migrate: Register invalid rotate " + rotate)
+		}
+
+		begin, end, err := getRotateRange(options[1])
+		if err != nil {
+			panic("migrate: Register invalid rotate " + rotate)
+		}
+
+		mi.Rotate = getRotate(options[0])
+		mi.RotateBegin = begin
+		mi.RotateEnd = end
+	}
+
+	// Go migration has no script, its checksum is computed by its identity
+	// skipcq: GSC-G401, GO-S1023
+	h := md5.New()
+	h.Write([]byte("go:" + module + ":" + version + ":" + strconv.Itoa(rank) + "_" + name + ":" + rotate))
+	mi.Checksum = fmt.Sprintf("%x", h.Sum(nil))
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, it := range registry[module] {
+		if it.Version == version && it.Migration.Rank == rank && it.Migration.Name == name {
+			panic("migrate: Register called twice for " + module + " " + version + " " + strconv.Itoa(rank) + "_" + name)
+		}
+	}
+
+	if registry == nil {
+		registry = make(map[string][]registeredMigration)
+	}
+
+	registry[module] = append(registry[module], registeredMigration{Version: version, Migration: mi})
+}
+
+// loadRegistry merges registered Go migrations of current module into discovered versions.
+func (m *Migrator) loadRegistry() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, it := range registry[m.module] {
+		i := m.findVersion(it.Version)
+		if i < 0 {
+			// version has been validated by Register
+			v, _ := parseSemver(it.Version)
+			m.Versions = append(m.Versions, v)
+			i = len(m.Versions) - 1
+		}
+
+		v := &m.Versions[i]
+		v.Migrations = append(v.Migrations, it.Migration)
+		sort.Stable(v)
+	}
+}
+
+// findVersion returns the index of version name in discovered versions, or -1 if it is not found.
+func (m *Migrator) findVersion(name string) int {
+	for i, v := range m.Versions {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// execMigration executes script or Go function of migration s on the table and its rotated tables.
+func execMigration(ctx context.Context, tx *sqle.Tx, s Migration, rotations []string) error {
+	if s.Func != nil {
+		for _, rn := range rotations {
+			err := s.Func(context.WithValue(ctx, rotatedNameKey{}, rn), tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, it := range buildStatements(s.Scripts, rotations) {
+		_, err := tx.ExecContext(ctx, it.SQL)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
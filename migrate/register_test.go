@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestRegister(t *testing.T) {
+	saved := registry
+	registry = nil
+	t.Cleanup(func() {
+		registry = saved
+	})
+
+	var rotatedNames []string
+	Register("tests", "0.1.0", 2, "seed_roles", func(ctx context.Context, tx *sqle.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO roles(id, name) VALUES(1, 'admin')")
+		return err
+	})
+	RegisterRotate("tests", "0.2.0", 1, "seed_logs", "monthly=20240201-20240301", func(ctx context.Context, tx *sqle.Tx) error {
+		rotatedNames = append(rotatedNames, RotatedName(ctx))
+		_, err := tx.ExecContext(ctx, "INSERT INTO logs"+RotatedName(ctx)+"(id) VALUES(1)")
+		return err
+	})
+
+	require.Panics(t, func() {
+		Register("tests", "0.1.0", 2, "seed_roles", func(context.Context, *sqle.Tx) error { return nil })
+	})
+	require.Panics(t, func() {
+		Register("tests", "v0.1", 1, "invalid_version", func(context.Context, *sqle.Tx) error { return nil })
+	})
+	require.Panics(t, func() {
+		RegisterRotate("tests", "0.1.0", 3, "invalid_rotate", "yearly=20240201-20240301", func(context.Context, *sqle.Tx) error { return nil })
+	})
+
+	// migrations of other modules are registered and tracked separately
+	Register("others", "0.1.0", 2, "seed_roles", func(context.Context, *sqle.Tx) error {
+		return errors.New("others")
+	})
+	require.NotEqual(t, registry["tests"][0].Migration.Checksum, registry["others"][0].Migration.Checksum)
+
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, name varchar(45) NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/3_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS users (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.1/1_create_table_logs.sql": &fstest.MapFile{
+			Data: []byte("/* rotate: monthly=20240201-20240301 */\nCREATE TABLE IF NOT EXISTS logs<rotate> (id int NOT NULL, PRIMARY KEY (id));"),
+		},
+	}
+
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+
+	require.Len(t, m.Versions, 3)
+	require.Equal(t, "0.2.0", m.Versions[2].Name)
+	require.Equal(t, "create_table_roles", m.Versions[0].Migrations[0].Name)
+	require.Equal(t, "seed_roles", m.Versions[0].Migrations[1].Name)
+	require.Equal(t, "create_table_users", m.Versions[0].Migrations[2].Name)
+
+	others := New(db)
+	require.NoError(t, others.Discover(fstest.MapFS{}, WithModule("others"), WithLogger(DiscardLogger)))
+	require.Len(t, others.Versions, 1)
+	require.Len(t, others.Versions[0].Migrations, 1)
+
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM roles WHERE id = 1").Scan(&name))
+	require.Equal(t, "admin", name)
+	require.Equal(t, []string{"", "_202402", "_202403"}, rotatedNames)
+
+	// checksum of Go migration is stable, so it is skipped on next run
+	m = New(db)
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Migrate(context.TODO()))
+	require.NoError(t, m.Verify(context.TODO()))
+	require.Len(t, rotatedNames, 3)
+}
+
+func TestRegisterFailed(t *testing.T) {
+	saved := registry
+	registry = nil
+	t.Cleanup(func() {
+		registry = saved
+	})
+
+	errSeed := errors.New("seed failed")
+	Register("tests", "0.1.0", 2, "seed_roles", func(ctx context.Context, tx *sqle.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO roles(id) VALUES(1)")
+		if err != nil {
+			return err
+		}
+		return errSeed
+	})
+
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+	require.ErrorIs(t, m.Migrate(context.TODO()), errSeed)
+
+	// version is rolled back with its sql scripts
+	var n int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migrations").Scan(&n))
+	require.Equal(t, 0, n)
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/yaitoo/sqle"
+)
+
+// ModifiedPolicy decides how Migrate handles a script that has been modified after it was executed.
+type ModifiedPolicy int
+
+const (
+	ModifiedWarn  ModifiedPolicy = iota // skip the script and emit EventScriptModified. It is the default policy
+	ModifiedFail                        // stop migrating with ErrMigrationModified
+	ModifiedRerun                       // execute the script again, and replace its record in sqle_migrations
+)
+
+// Repair rewrites stored checksums and scripts of modified scripts on all databases, and for every tenant if tenants are
+// configured, after they are edited intentionally, so they are treated as executed without executing them again.
+func (m *Migrator) Repair(ctx context.Context) error {
+	for i, db := range m.dbs {
+		m.emit(ctx, Event{Type: EventStarted, Action: "repair", DB: i})
+
+		err := m.withLock(ctx, db, func(ctx context.Context) error {
+			return m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+				return tm.startRepair(ctx, i, db)
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) startRepair(ctx context.Context, i int, db *sqle.DB) error {
+	for _, v := range m.Versions {
+		n := len(v.Migrations)
+		for j, s := range v.Migrations {
+			status, err := m.getMigrationStatus(ctx, db, v.Name, s)
+			if err != nil {
+				return err
+			}
+
+			if status != MigrationStatusModified {
+				continue
+			}
+
+			_, err = db.ExecBuilder(ctx, m.dialect.builder("UPDATE sqle_migrations SET checksum = {checksum}, scripts = {scripts} WHERE module = {module} AND tenant = {tenant} AND version = {version} AND name = {name} AND "+m.dialect.quote("rank")+" = {rank}").
+				Param("checksum", s.Checksum).
+				Param("scripts", m.dialect.value(s.Scripts)).
+				Param("module", m.dialect.value(m.module)).
+				Param("tenant", m.dialect.value(m.tenant)).
+				Param("version", v.Name).
+				Param("name", s.Name).
+				Param("rank", s.Rank))
+			if err != nil {
+				return err
+			}
+
+			m.emit(ctx, Event{Type: EventScriptRepaired, Action: "repair", DB: i, Version: v.Name, Name: s.Name, Rank: s.Rank, Index: j + 1, Total: n})
+		}
+	}
+
+	return nil
+}
+
+// Baseline marks all scripts of versions up to version as executed without executing them on all databases, and for
+// every tenant if tenants are configured.
+// It is used to adopt an existing database whose schema has been created by other tools.
+func (m *Migrator) Baseline(ctx context.Context, version string) error {
+	target, err := parseSemver(version)
+	if err != nil {
+		return err
+	}
+
+	for i, db := range m.dbs {
+		m.emit(ctx, Event{Type: EventStarted, Action: "baseline", DB: i})
+
+		err = m.withLock(ctx, db, func(ctx context.Context) error {
+			return m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+				return db.Transaction(ctx, nil, func(ctx context.Context, tx *sqle.Tx) error {
+					return tm.startBaseline(ctx, i, tx, target)
+				})
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) startBaseline(ctx context.Context, i int, tx *sqle.Tx, target Semver) error {
+	now := time.Now()
+	for _, v := range m.Versions {
+		if lessSemver(target, v) {
+			break
+		}
+
+		n := len(v.Migrations)
+		for j, s := range v.Migrations {
+			status, err := m.getMigrationStatus(ctx, tx, v.Name, s)
+			if err != nil {
+				return err
+			}
+
+			if status != MigrationStatusNew {
+				continue
+			}
+
+			err = m.insertMigration(ctx, tx, v.Name, s, now, 0)
+			if err != nil {
+				return err
+			}
+
+			m.emit(ctx, Event{Type: EventScriptBaselined, Action: "baseline", DB: i, Version: v.Name, Name: s.Name, Rank: s.Rank, Index: j + 1, Total: n})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestModifiedPolicy(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	modified := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));
+INSERT INTO roles (id) VALUES (1);`),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		policy ModifiedPolicy
+		assert func(t *testing.T, db *sqle.DB, reports []Report, err error)
+	}{
+		{
+			name:   "warn_should_skip",
+			policy: ModifiedWarn,
+			assert: func(t *testing.T, db *sqle.DB, reports []Report, err error) {
+				require.NoError(t, err)
+				require.Len(t, reports[0].Skipped, 1)
+				require.Equal(t, MigrationStatusModified, reports[0].Skipped[0].Status)
+			},
+		},
+		{
+			name:   "fail_should_stop",
+			policy: ModifiedFail,
+			assert: func(t *testing.T, db *sqle.DB, reports []Report, err error) {
+				require.ErrorIs(t, err, ErrMigrationModified)
+				require.Len(t, reports[0].Failed, 1)
+			},
+		},
+		{
+			name:   "rerun_should_execute_again",
+			policy: ModifiedRerun,
+			assert: func(t *testing.T, db *sqle.DB, reports []Report, err error) {
+				require.NoError(t, err)
+				require.Len(t, reports[0].Applied, 1)
+
+				var n int
+				require.NoError(t, db.QueryRow("SELECT count(*) FROM roles").Scan(&n))
+				require.Equal(t, 1, n)
+				require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migrations").Scan(&n))
+				require.Equal(t, 1, n)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, clean, err := createSqlite3()
+			defer clean()
+			require.NoError(t, err)
+			db := sqle.Open(d)
+
+			m := New(db)
+			require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+			require.NoError(t, m.Init(context.TODO()))
+			require.NoError(t, m.Migrate(context.TODO()))
+
+			m = New(db)
+			require.NoError(t, m.Discover(modified, WithModule("tests"), WithLogger(DiscardLogger), WithModifiedPolicy(test.policy)))
+			reports, err := m.MigrateWithReport(context.TODO())
+			test.assert(t, db, reports, err)
+		})
+	}
+}
+
+func TestRepair(t *testing.T) {
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+
+	m = New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`-- formatted
+CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.ErrorIs(t, m.Verify(context.TODO()), ErrMigrationModified)
+
+	require.NoError(t, m.Repair(context.TODO()))
+	require.NoError(t, m.Verify(context.TODO()))
+
+	var scripts string
+	require.NoError(t, db.QueryRow("SELECT scripts FROM sqle_migrations").Scan(&scripts))
+	require.Equal(t, m.Versions[0].Migrations[0].Scripts, scripts)
+}
+
+func TestBaseline(t *testing.T) {
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	// schema has been created by other tools
+	_, err = db.Exec("CREATE TABLE roles (id int NOT NULL, PRIMARY KEY (id))")
+	require.NoError(t, err)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.2.0/1_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE users (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+
+	require.ErrorIs(t, m.Baseline(context.TODO(), "v1"), ErrInvalidVersion)
+	require.NoError(t, m.Baseline(context.TODO(), "0.1.0"))
+
+	reports, err := m.MigrateWithReport(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, reports[0].Skipped, 1)
+	require.Len(t, reports[0].Applied, 1)
+	require.Equal(t, "create_table_users", reports[0].Applied[0].Name)
+}
@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yaitoo/sqle"
+)
+
+// Report is the result of migrating a database.
+type Report struct {
+	DB       int // index of database in Migrator
+	Applied  []ScriptReport
+	Skipped  []ScriptReport // scripts that have been executed or modified
+	Failed   []ScriptReport
+	Duration time.Duration
+	Err      error
+}
+
+// ScriptReport is the result of a script on a database.
+type ScriptReport struct {
+	Tenant   string // tenant that script is migrated for, it is empty if tenants are not configured
+	Version  string
+	Name     string
+	Rank     int
+	Status   MigrationStatus
+	Duration time.Duration
+	Err      error
+}
+
+// MigrateWithReport migrates all discovered versions on all databases, and returns a report for every database
+// that has been started. Databases are migrated concurrently by WithConcurrency, and later databases are skipped
+// on the first error unless WithContinueOnError is enabled.
+func (m *Migrator) MigrateWithReport(ctx context.Context) ([]Report, error) {
+	reports := make([]Report, len(m.dbs))
+	started := make([]bool, len(m.dbs))
+
+	concurrency := m.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var failed int32
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for i, db := range m.dbs {
+		sem <- struct{}{}
+		if !m.continueOnError && atomic.LoadInt32(&failed) > 0 {
+			<-sem
+			break
+		}
+
+		started[i] = true
+		wg.Add(1)
+		go func(i int, db *sqle.DB) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			reports[i] = m.migrateDB(ctx, i, db)
+			if reports[i].Err != nil {
+				atomic.StoreInt32(&failed, 1)
+			}
+		}(i, db)
+	}
+
+	wg.Wait()
+
+	var items []Report
+	var errs []error
+	for i, r := range reports {
+		if !started[i] {
+			continue
+		}
+
+		items = append(items, r)
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+
+	return items, errors.Join(errs...)
+}
+
+func (m *Migrator) migrateDB(ctx context.Context, i int, db *sqle.DB) Report {
+	m.emit(ctx, Event{Type: EventStarted, Action: "migrate", DB: i})
+
+	r := Report{DB: i}
+	now := time.Now()
+	r.Err = m.withLock(ctx, db, func(ctx context.Context) error {
+		return m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+			return tm.startMigrate(ctx, db, &r)
+		})
+	})
+	r.Duration = time.Since(now)
+
+	return r
+}
@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestMigrateWithReport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/2_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE users (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.2.0/1_create_table_orders.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS orders (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	setup := func(t *testing.T, options ...Option) *Migrator {
+		var dbs []*sqle.DB
+		for i := 0; i < 3; i++ {
+			d, clean, err := createSqlite3()
+			t.Cleanup(clean)
+			require.NoError(t, err)
+			dbs = append(dbs, sqle.Open(d))
+		}
+
+		// users has been created on db-1 without migration
+		_, err := dbs[1].Exec("CREATE TABLE users (id int NOT NULL, PRIMARY KEY (id));")
+		require.NoError(t, err)
+
+		m := New(dbs...)
+		require.NoError(t, m.Discover(fsys, options...))
+		require.NoError(t, m.Init(context.TODO()))
+		return m
+	}
+
+	t.Run("should_stop_on_error", func(t *testing.T) {
+		m := setup(t)
+
+		reports, err := m.MigrateWithReport(context.TODO())
+		require.ErrorContains(t, err, "table users already exists")
+		require.Len(t, reports, 2)
+
+		require.Equal(t, 0, reports[0].DB)
+		require.NoError(t, reports[0].Err)
+		require.Len(t, reports[0].Applied, 3)
+		require.Empty(t, reports[0].Failed)
+
+		require.Equal(t, 1, reports[1].DB)
+		require.Error(t, reports[1].Err)
+		require.Empty(t, reports[1].Applied)
+		require.Len(t, reports[1].Failed, 1)
+		require.Equal(t, "create_table_users", reports[1].Failed[0].Name)
+		require.Equal(t, "0.1.0", reports[1].Failed[0].Version)
+		require.Error(t, reports[1].Failed[0].Err)
+
+		reports, err = m.MigrateWithReport(context.TODO())
+		require.Error(t, err)
+		require.Len(t, reports, 2)
+		require.Empty(t, reports[0].Applied)
+		require.Len(t, reports[0].Skipped, 3)
+	})
+
+	t.Run("should_continue_on_error_concurrently", func(t *testing.T) {
+		m := setup(t, WithConcurrency(3), WithContinueOnError())
+
+		reports, err := m.MigrateWithReport(context.TODO())
+		require.ErrorContains(t, err, "table users already exists")
+		require.Len(t, reports, 3)
+
+		for _, i := range []int{0, 2} {
+			require.Equal(t, i, reports[i].DB)
+			require.NoError(t, reports[i].Err)
+			require.Len(t, reports[i].Applied, 3)
+			require.Equal(t, MigrationStatusNew, reports[i].Applied[0].Status)
+		}
+
+		require.Error(t, reports[1].Err)
+		require.Len(t, reports[1].Failed, 1)
+	})
+}
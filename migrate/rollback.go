@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yaitoo/sqle"
+)
+
+// Rollback reverts executed versions that are greater than toVersion in reverse order on all databases, and for every
+// tenant if tenants are configured.
+// Every script is reverted by its paired down script, which is expanded on the table and all its rotated tables
+// when the script has a rotate header. All versions are reverted if toVersion is empty.
+func (m *Migrator) Rollback(ctx context.Context, toVersion string) error {
+	_, err := m.getRollbackVersions(toVersion)
+	if err != nil {
+		return err
+	}
+
+	for i, db := range m.dbs {
+		m.emit(ctx, Event{Type: EventStarted, Action: "rollback", DB: i})
+
+		err = m.withLock(ctx, db, func(ctx context.Context) error {
+			return m.eachTenant(ctx, db, func(ctx context.Context, tm *Migrator) error {
+				versions, err := tm.getRollbackVersions(toVersion)
+				if err != nil {
+					return err
+				}
+				return tm.startRollback(ctx, i, db, versions)
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getRollbackVersions returns versions that are greater than toVersion in reverse order.
+func (m *Migrator) getRollbackVersions(toVersion string) ([]Semver, error) {
+	var target *Semver
+	if toVersion != "" {
+		v, err := parseSemver(toVersion)
+		if err != nil {
+			return nil, err
+		}
+		target = &v
+	}
+
+	var versions []Semver
+	for i := len(m.Versions) - 1; i >= 0; i-- {
+		v := m.Versions[i]
+		if target != nil && !lessSemver(*target, v) {
+			break
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// parseSemver parses version name to Semver.
+func parseSemver(name string) (Semver, error) {
+	matches := regexpSemver.FindStringSubmatch(name)
+	if len(matches) != 6 {
+		return Semver{}, ErrInvalidVersion
+	}
+
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	patch, _ := strconv.Atoi(matches[3])
+
+	return Semver{
+		Name:       matches[0],
+		Major:      major,
+		Minor:      minor,
+		Patch:      patch,
+		Prerelease: matches[4],
+		Build:      matches[5],
+	}, nil
+}
+
+func (m *Migrator) startRollback(ctx context.Context, i int, db *sqle.DB, versions []Semver) error {
+	// all executed scripts should have down scripts, otherwise nothing is reverted
+	for _, v := range versions {
+		for _, s := range v.Migrations {
+			if s.DownScripts != "" {
+				continue
+			}
+
+			status, err := m.getMigrationStatus(ctx, db, v.Name, s)
+			if err != nil {
+				return err
+			}
+
+			if status != MigrationStatusNew {
+				return fmt.Errorf("%w: v%s %s", ErrMissingDownScript, v.Name, s.File)
+			}
+		}
+	}
+
+	var err error
+	for _, v := range versions {
+		n := len(v.Migrations)
+		m.emit(ctx, Event{Type: EventVersionStarted, Action: "rollback", DB: i, Version: v.Name})
+		err = db.Transaction(ctx, nil, func(ctx context.Context, tx *sqle.Tx) error {
+			for j := n - 1; j >= 0; j-- {
+				s := v.Migrations[j]
+				status, err := m.getMigrationStatus(ctx, tx, v.Name, s)
+				if err != nil {
+					return err
+				}
+
+				e := Event{Action: "rollback", DB: i, Version: v.Name, Name: s.Name, Rank: s.Rank, Index: j + 1, Total: n}
+				if status == MigrationStatusNew {
+					e.Type = EventScriptPending
+					m.emit(ctx, e)
+					continue
+				}
+
+				rotations := m.buildRotations(s.Rotate, s.RotateBegin, s.RotateEnd)
+
+				now := time.Now()
+				for _, it := range buildStatements(s.DownScripts, rotations) {
+					_, err = tx.ExecContext(ctx, it.SQL)
+					if err != nil {
+						return err
+					}
+				}
+
+				err = m.deleteMigration(ctx, tx, v.Name, s)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.ExecBuilder(ctx, m.dialect.builder("DELETE FROM sqle_migration_progress WHERE checksum = {checksum}").
+					Param("checksum", s.Checksum))
+				if err != nil {
+					return err
+				}
+
+				e.Type = EventScriptReverted
+				e.Rotations = rotations
+				e.Duration = time.Since(now)
+				m.emit(ctx, e)
+			}
+
+			m.emit(ctx, Event{Type: EventVersionCompleted, Action: "rollback", DB: i, Version: v.Name})
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		err = m.refreshLock(ctx, db)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
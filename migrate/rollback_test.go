@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestRollback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.1.0/1_create_table_roles.down.sql": &fstest.MapFile{
+			Data: []byte(`DROP TABLE IF EXISTS roles;`),
+		},
+		"0.2.0/1_create_monthly_logs.sql": &fstest.MapFile{
+			Data: []byte(`/* rotate: monthly = 20240201 - 20240301 */
+			CREATE TABLE IF NOT EXISTS monthly_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.2.0/1_create_monthly_logs.down.sql": &fstest.MapFile{
+			Data: []byte(`DROP TABLE IF EXISTS monthly_logs<rotate>;`),
+		},
+		"0.2.0/2_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS users (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.2.0/2_create_table_users.down.sql": &fstest.MapFile{
+			Data: []byte(`DROP TABLE IF EXISTS users;`),
+		},
+	}
+
+	tableExists := func(db *sqle.DB, name string) bool {
+		var n int
+		err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type='table' AND name=?", name).Scan(&n)
+		require.NoError(t, err)
+		return n > 0
+	}
+
+	countMigrations := func(db *sqle.DB) int {
+		var n int
+		err := db.QueryRow("SELECT count(*) FROM sqle_migrations").Scan(&n)
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("rollback_to_version_should_work", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+
+		db := sqle.Open(d)
+		m := New(db)
+		require.NoError(t, m.Discover(fsys, WithModule("tests")))
+		require.Equal(t, "DROP TABLE IF EXISTS roles;", m.Versions[0].Migrations[0].DownScripts)
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Migrate(context.TODO()))
+
+		for _, it := range []string{"roles", "users", "monthly_logs", "monthly_logs_202402", "monthly_logs_202403"} {
+			require.True(t, tableExists(db, it))
+		}
+		require.Equal(t, 3, countMigrations(db))
+
+		require.NoError(t, m.Rollback(context.TODO(), "0.1.0"))
+
+		require.True(t, tableExists(db, "roles"))
+		for _, it := range []string{"users", "monthly_logs", "monthly_logs_202402", "monthly_logs_202403"} {
+			require.False(t, tableExists(db, it))
+		}
+		require.Equal(t, 1, countMigrations(db))
+
+		// rolled back versions can be migrated again
+		require.NoError(t, m.Migrate(context.TODO()))
+		require.True(t, tableExists(db, "users"))
+		require.Equal(t, 3, countMigrations(db))
+
+		require.NoError(t, m.Rollback(context.TODO(), ""))
+		require.False(t, tableExists(db, "roles"))
+		require.Equal(t, 0, countMigrations(db))
+	})
+
+	t.Run("invalid_version_should_not_work", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+
+		m := New(sqle.Open(d))
+		require.NoError(t, m.Discover(fsys))
+		require.ErrorIs(t, m.Rollback(context.TODO(), "v1"), ErrInvalidVersion)
+	})
+
+	t.Run("missing_down_script_should_not_work", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+
+		db := sqle.Open(d)
+		m := New(db)
+		require.NoError(t, m.Discover(fstest.MapFS{
+			"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+				Data: []byte(`CREATE TABLE IF NOT EXISTS roles (id int NOT NULL, PRIMARY KEY (id));`),
+			},
+		}))
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Migrate(context.TODO()))
+
+		require.ErrorIs(t, m.Rollback(context.TODO(), ""), ErrMissingDownScript)
+		require.True(t, tableExists(db, "roles"))
+	})
+}
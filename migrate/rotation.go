@@ -17,6 +17,18 @@ type Rotation struct {
 	Name     string
 	Checksum string
 	Script   string
+
+	Retain     int    // number of RetainUnit that rotated tables are kept, rotated tables are kept forever if it is 0
+	RetainUnit string // day, week or month
+	Archive    bool   // expired rotated tables are renamed to archive instead of being dropped
+
+	Ahead  int // number of periods after now that rotated tables are created for, the option of Migrator is used if it is 0
+	Behind int // number of periods before now that rotated tables are created for, the option of Migrator is used if it is 0
+}
+
+type rotationGroup struct {
+	Rotate    shardid.TableRotate
+	Rotations []Rotation
 }
 
 func getRotate(option string) shardid.TableRotate {
@@ -99,9 +111,101 @@ func loadRotations(fsys fs.FS, d string) ([]Rotation, error) {
 
 			it.Checksum = fmt.Sprintf("%x", h.Sum(nil))
 
+			headers := parseHeaders(s)
+			err = it.parseRetention(headers)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, dn)
+			}
+
+			err = it.parseLookAhead(headers)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, dn)
+			}
+
 			items = append(items, it)
 		}
 	}
 
 	return items, nil
 }
+
+// parseRetention parses retention headers of rotation script. eg
+//
+//	/* retain: 12 months */
+//	/* prune: archive */
+func (r *Rotation) parseRetention(headers map[string]string) error {
+	retain, ok := headers["retain"]
+	if ok {
+		fields := strings.Fields(retain)
+		if len(fields) != 2 {
+			return ErrInvalidRetention
+		}
+
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 {
+			return ErrInvalidRetention
+		}
+
+		switch strings.TrimSuffix(strings.ToLower(fields[1]), "s") {
+		case "day":
+			r.RetainUnit = "day"
+		case "week":
+			r.RetainUnit = "week"
+		case "month":
+			r.RetainUnit = "month"
+		default:
+			return ErrInvalidRetention
+		}
+
+		r.Retain = n
+	}
+
+	switch strings.ToLower(headers["prune"]) {
+	case "", "drop":
+	case "archive":
+		r.Archive = true
+	default:
+		return ErrInvalidRetention
+	}
+
+	return nil
+}
+
+// parseLookAhead parses look-ahead headers of rotation script. eg
+//
+//	/* ahead: 7 */
+//	/* behind: 2 */
+func (r *Rotation) parseLookAhead(headers map[string]string) error {
+	for k, v := range map[string]*int{"ahead": &r.Ahead, "behind": &r.Behind} {
+		h, ok := headers[k]
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.Atoi(h)
+		if err != nil || n < 1 {
+			return ErrInvalidLookAhead
+		}
+		*v = n
+	}
+
+	return nil
+}
+
+// getRetainTime returns the earliest time that is kept by retention of rotation r.
+func (r Rotation) getRetainTime(now time.Time) time.Time {
+	switch r.RetainUnit {
+	case "month":
+		// month of now.AddDate(0, -n, 0) is skipped on the 31st
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -r.Retain, 0)
+		day := now.Day()
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+	case "week":
+		return now.AddDate(0, 0, -7*r.Retain)
+	default:
+		return now.AddDate(0, 0, -r.Retain)
+	}
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLookAhead(t *testing.T) {
+	var r Rotation
+	require.NoError(t, r.parseLookAhead(parseHeaders("/* ahead: 7 */\n/* behind: 2 */\nCREATE TABLE IF NOT EXISTS logs<rotate> (id int);")))
+	require.Equal(t, 7, r.Ahead)
+	require.Equal(t, 2, r.Behind)
+
+	require.ErrorIs(t, r.parseLookAhead(parseHeaders("/* ahead: 0 */")), ErrInvalidLookAhead)
+	require.ErrorIs(t, r.parseLookAhead(parseHeaders("/* behind: one */")), ErrInvalidLookAhead)
+}
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    Rotation
+		wantErr error
+	}{
+		{
+			name:   "no_retention",
+			script: "CREATE TABLE IF NOT EXISTS logs<rotate> (id int);",
+		},
+		{
+			name:   "months",
+			script: "/* retain: 12 months */\nCREATE TABLE IF NOT EXISTS logs<rotate> (id int);",
+			want:   Rotation{Retain: 12, RetainUnit: "month"},
+		},
+		{
+			name:   "archive",
+			script: "/* prune: archive */\n/* retain: 1 Week */\nCREATE TABLE IF NOT EXISTS logs<rotate> (id int);",
+			want:   Rotation{Retain: 1, RetainUnit: "week", Archive: true},
+		},
+		{
+			name:    "invalid_unit",
+			script:  "/* retain: 12 years */\nCREATE TABLE IF NOT EXISTS logs<rotate> (id int);",
+			wantErr: ErrInvalidRetention,
+		},
+		{
+			name:    "invalid_period",
+			script:  "/* retain: 0 days */\nCREATE TABLE IF NOT EXISTS logs<rotate> (id int);",
+			wantErr: ErrInvalidRetention,
+		},
+		{
+			name:    "invalid_action",
+			script:  "/* retain: 1 days */\n/* prune: truncate */\nCREATE TABLE IF NOT EXISTS logs<rotate> (id int);",
+			wantErr: ErrInvalidRetention,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var r Rotation
+			err := r.parseRetention(parseHeaders(test.script))
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.want, r)
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yaitoo/sqle"
+)
+
+// regexpRotatedName matches a rotated table or an archived rotated table. eg `logs_202402` or `logs_20240201_archive`
+var regexpRotatedName = regexp.MustCompile(`^(.+)(_\d{6,8})(_archive)?$`)
+
+// Schema is a normalized snapshot of tables in a database. Names and types are in lowercase, tables and indexes are
+// sorted by name, and columns are in their ordinal order. Bookkeeping tables of Migrator are excluded. Rotated tables
+// of a table that is created with <rotate> are collapsed into one table named `{table}<rotate>`.
+type Schema struct {
+	Tables []Table `json:"tables"`
+}
+
+// Table is a table in Schema.
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	Indexes []Index  `json:"indexes,omitempty"`
+}
+
+// Column is a column of Table.
+type Column struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// Index is an index of Table.
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+}
+
+// WriteSchema writes s as indented JSON to w, so it can be committed with migrations.
+func WriteSchema(w io.Writer, s Schema) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ReadSchema reads a Schema that is written by WriteSchema from r.
+func ReadSchema(r io.Reader) (Schema, error) {
+	var s Schema
+	err := json.NewDecoder(r).Decode(&s)
+	return s, err
+}
+
+// Snapshot introspects live schema of all databases by information_schema, sqlite_master or data dictionary of
+// the dialect.
+func (m *Migrator) Snapshot(ctx context.Context) ([]Schema, error) {
+	items := make([]Schema, 0, len(m.dbs))
+	for _, db := range m.dbs {
+		s, err := m.getSchema(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+	}
+
+	return items, nil
+}
+
+func (m *Migrator) getSchema(ctx context.Context, db *sqle.DB) (Schema, error) {
+	var (
+		columns, indexes string
+		err              error
+		tables           = make(map[string]*Table)
+	)
+
+	switch m.dialect.Name {
+	case SQLite.Name:
+		err = loadSQLiteSchema(ctx, db, tables)
+		if err != nil {
+			return Schema{}, err
+		}
+	case Postgres.Name:
+		columns = `SELECT table_name, column_name, CASE WHEN character_maximum_length IS NULL THEN data_type ELSE data_type || '(' || character_maximum_length || ')' END, is_nullable = 'YES'
+FROM information_schema.columns WHERE table_schema = current_schema() ORDER BY table_name, ordinal_position`
+		indexes = `SELECT t.relname, i.relname, ix.indisunique, a.attname
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = current_schema() ORDER BY t.relname, i.relname, k.ord`
+	case Oracle.Name:
+		columns = `SELECT table_name, column_name, data_type, CASE WHEN nullable = 'Y' THEN 1 ELSE 0 END
+FROM user_tab_columns ORDER BY table_name, column_id`
+		indexes = `SELECT c.table_name, c.index_name, CASE WHEN i.uniqueness = 'UNIQUE' THEN 1 ELSE 0 END, c.column_name
+FROM user_ind_columns c JOIN user_indexes i ON i.index_name = c.index_name ORDER BY c.table_name, c.index_name, c.column_position`
+	default:
+		columns = `SELECT table_name, column_name, column_type, is_nullable = 'YES'
+FROM information_schema.columns WHERE table_schema = DATABASE() ORDER BY table_name, ordinal_position`
+		indexes = `SELECT table_name, index_name, non_unique = 0, column_name
+FROM information_schema.statistics WHERE table_schema = DATABASE() ORDER BY table_name, index_name, seq_in_index`
+	}
+
+	if columns != "" {
+		err = loadColumns(ctx, db, columns, tables)
+		if err != nil {
+			return Schema{}, err
+		}
+
+		err = loadIndexes(ctx, db, indexes, tables)
+		if err != nil {
+			return Schema{}, err
+		}
+	}
+
+	collapseRotatedTables(tables, m.getRotatedBases())
+
+	var s Schema
+	for _, t := range tables {
+		if strings.HasPrefix(t.Name, "sqle_") {
+			continue
+		}
+
+		sort.Slice(t.Indexes, func(i, j int) bool {
+			return t.Indexes[i].Name < t.Indexes[j].Name
+		})
+		s.Tables = append(s.Tables, *t)
+	}
+
+	sort.Slice(s.Tables, func(i, j int) bool {
+		return s.Tables[i].Name < s.Tables[j].Name
+	})
+
+	return s, nil
+}
+
+// getRotatedBases returns names of tables that are created with <rotate> in discovered scripts and rotations.
+func (m *Migrator) getRotatedBases() map[string]bool {
+	bases := make(map[string]bool)
+	add := func(script string) {
+		for _, t := range getRotatedTables(script) {
+			// live tables are loaded without schema
+			bases[strings.ToLower(t[strings.LastIndex(t, ".")+1:])] = true
+		}
+	}
+
+	for _, v := range m.Versions {
+		for _, s := range v.Migrations {
+			add(s.Scripts)
+		}
+	}
+
+	for _, g := range m.getRotations() {
+		for _, r := range g.Rotations {
+			add(r.Script)
+		}
+	}
+
+	return bases
+}
+
+// collapseRotatedTables replaces rotated tables of bases with one table named `{table}<rotate>`, or
+// `{table}<rotate>_archive` for archived ones. It has columns and indexes of the latest rotated table, and the suffix in
+// its index names is replaced with <rotate>. Rotated tables are created and pruned every period, so they would drift
+// between databases and committed snapshots otherwise.
+func collapseRotatedTables(tables map[string]*Table, bases map[string]bool) {
+	var rotated []string
+	latest := make(map[string]string) // collapsed name => name of its latest rotated table
+	suffixes := make(map[string]string)
+	for name := range tables {
+		it := regexpRotatedName.FindStringSubmatch(name)
+		if it == nil || !bases[it[1]] {
+			continue
+		}
+
+		rotated = append(rotated, name)
+		suffixes[name] = it[2]
+
+		// rotated names of a rotate type have same length, so they are sorted in time
+		collapsed := it[1] + "<rotate>" + it[3]
+		if name > latest[collapsed] {
+			latest[collapsed] = name
+		}
+	}
+
+	collapsedTables := make(map[string]*Table, len(latest))
+	for collapsed, name := range latest {
+		t := tables[name]
+		indexes := make([]Index, 0, len(t.Indexes))
+		for _, idx := range t.Indexes {
+			idx.Name = strings.ReplaceAll(idx.Name, suffixes[name], "<rotate>")
+			indexes = append(indexes, idx)
+		}
+
+		collapsedTables[collapsed] = &Table{Name: collapsed, Columns: t.Columns, Indexes: indexes}
+	}
+
+	for _, name := range rotated {
+		delete(tables, name)
+	}
+
+	for collapsed, t := range collapsedTables {
+		tables[collapsed] = t
+	}
+}
+
+// getTable returns the table of name in tables, it is created if it doesn't exist.
+func getTable(tables map[string]*Table, name string) *Table {
+	name = strings.ToLower(name)
+	t, ok := tables[name]
+	if !ok {
+		t = &Table{Name: name}
+		tables[name] = t
+	}
+	return t
+}
+
+// loadColumns loads columns by query that returns table, column, type and nullable.
+func loadColumns(ctx context.Context, db *sqle.DB, query string, tables map[string]*Table) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+		var c Column
+		err = rows.Scan(&table, &c.Name, &c.Type, &c.Nullable)
+		if err != nil {
+			return err
+		}
+
+		c.Name = strings.ToLower(c.Name)
+		c.Type = strings.ToLower(c.Type)
+
+		t := getTable(tables, table)
+		t.Columns = append(t.Columns, c)
+	}
+
+	return rows.Err()
+}
+
+// loadIndexes loads indexes by query that returns table, index, unique and column ordered by column position.
+func loadIndexes(ctx context.Context, db *sqle.DB, query string, tables map[string]*Table) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, index, column string
+		var unique bool
+		err = rows.Scan(&table, &index, &unique, &column)
+		if err != nil {
+			return err
+		}
+
+		t := getTable(tables, table)
+		addIndexColumn(t, strings.ToLower(index), unique, strings.ToLower(column))
+	}
+
+	return rows.Err()
+}
+
+// addIndexColumn appends column into index of table t.
+func addIndexColumn(t *Table, index string, unique bool, column string) {
+	for i, it := range t.Indexes {
+		if it.Name == index {
+			t.Indexes[i].Columns = append(it.Columns, column)
+			return
+		}
+	}
+
+	t.Indexes = append(t.Indexes, Index{Name: index, Unique: unique, Columns: []string{column}})
+}
+
+// loadSQLiteSchema loads tables, columns and indexes by sqlite_master and pragma functions.
+func loadSQLiteSchema(ctx context.Context, db *sqle.DB, tables map[string]*Table) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		name = strings.ReplaceAll(name, "'", "''")
+		err = loadColumns(ctx, db, "SELECT '"+name+"', name, type, \"notnull\" = 0 FROM pragma_table_info('"+name+"') ORDER BY cid", tables)
+		if err != nil {
+			return err
+		}
+
+		err = loadIndexes(ctx, db, "SELECT '"+name+"', l.name, l.\"unique\", i.name FROM pragma_index_list('"+name+"') l JOIN pragma_index_info(l.name) i ORDER BY l.name, i.seqno", tables)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
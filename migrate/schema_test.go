@@ -0,0 +1,160 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestSnapshot(t *testing.T) {
+	d0, clean0, err := createSqlite3()
+	defer clean0()
+	require.NoError(t, err)
+
+	d1, clean1, err := createSqlite3()
+	defer clean1()
+	require.NoError(t, err)
+
+	m := New(sqle.Open(d0), sqle.Open(d1))
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS users (
+				id int NOT NULL,
+				email varchar(45) NOT NULL,
+				name varchar(45),
+				PRIMARY KEY (id)
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);`),
+		},
+	}, WithModule("tests"), WithDialect(SQLite), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+
+	items, err := m.Snapshot(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, items[0], items[1])
+
+	require.Equal(t, Schema{Tables: []Table{
+		{
+			Name: "users",
+			Columns: []Column{
+				{Name: "id", Type: "int"},
+				{Name: "email", Type: "varchar(45)"},
+				{Name: "name", Type: "varchar(45)", Nullable: true},
+			},
+			Indexes: []Index{
+				{Name: "idx_users_email", Columns: []string{"email"}, Unique: true},
+				{Name: "sqlite_autoindex_users_1", Columns: []string{"id"}, Unique: true},
+			},
+		},
+	}}, items[0])
+
+	drifts, err := m.Drift(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSchema(&buf, items[0]))
+	expected, err := ReadSchema(&buf)
+	require.NoError(t, err)
+	require.Equal(t, items[0], expected)
+
+	// schema is changed outside of migrations
+	_, err = d1.Exec("ALTER TABLE users ADD COLUMN age int")
+	require.NoError(t, err)
+	_, err = d1.Exec("DROP INDEX idx_users_email")
+	require.NoError(t, err)
+	_, err = d1.Exec("CREATE INDEX idx_users_email ON users (email, name)")
+	require.NoError(t, err)
+	_, err = d1.Exec("CREATE TABLE logs (id int)")
+	require.NoError(t, err)
+
+	drifts, err = m.Drift(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []Drift{
+		{DB: 1, Kind: DriftUnexpected, Table: "users", Column: "age"},
+		{DB: 1, Kind: DriftChanged, Table: "users", Index: "idx_users_email", Expected: "UNIQUE (email)", Actual: "(email, name)"},
+		{DB: 1, Kind: DriftUnexpected, Table: "logs"},
+	}, drifts)
+	require.Equal(t, "db-1 users.age unexpected", drifts[0].String())
+
+	expected.Tables = append(expected.Tables, Table{Name: "roles", Columns: []Column{{Name: "id", Type: "int"}}})
+	drifts, err = m.Drift(context.TODO(), &expected)
+	require.NoError(t, err)
+	require.Contains(t, drifts, Drift{DB: 0, Kind: DriftMissing, Table: "roles"})
+	require.Contains(t, drifts, Drift{DB: 1, Kind: DriftMissing, Table: "roles"})
+	require.Len(t, drifts, 5)
+}
+
+func TestSnapshotRotatedTables(t *testing.T) {
+	d0, clean0, err := createSqlite3()
+	defer clean0()
+	require.NoError(t, err)
+
+	d1, clean1, err := createSqlite3()
+	defer clean1()
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		"monthly/monthly_logs.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS monthly_logs<rotate> (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	// databases are rotated in different periods
+	for i, d := range []*sql.DB{d0, d1} {
+		month := time.Month(2 + i)
+		m := New(sqle.Open(d))
+		m.now = func() time.Time {
+			return time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC)
+		}
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger)))
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Rotate(context.TODO()))
+	}
+
+	// it is not created with <rotate>
+	_, err = d0.Exec("CREATE TABLE orders_123456 (id int)")
+	require.NoError(t, err)
+
+	m := New(sqle.Open(d0), sqle.Open(d1))
+	require.NoError(t, m.Discover(fsys, WithModule("tests"), WithDialect(SQLite), WithLogger(DiscardLogger)))
+
+	items, err := m.Snapshot(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, Schema{Tables: []Table{
+		{
+			Name:    "monthly_logs<rotate>",
+			Columns: []Column{{Name: "id", Type: "int"}},
+			Indexes: []Index{{Name: "sqlite_autoindex_monthly_logs<rotate>_1", Columns: []string{"id"}, Unique: true}},
+		},
+		{
+			Name:    "orders_123456",
+			Columns: []Column{{Name: "id", Type: "int", Nullable: true}},
+		},
+	}}, items[0])
+
+	_, err = d0.Exec("DROP TABLE orders_123456")
+	require.NoError(t, err)
+
+	drifts, err := m.Drift(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	// latest rotated table is changed outside of migrations
+	_, err = d1.Exec("ALTER TABLE monthly_logs_202404 ADD COLUMN msg text")
+	require.NoError(t, err)
+
+	drifts, err = m.Drift(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []Drift{
+		{DB: 1, Kind: DriftUnexpected, Table: "monthly_logs<rotate>", Column: "msg"},
+	}, drifts)
+}
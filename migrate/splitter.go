@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"strings"
+)
+
+// splitStatements splits script into statements by delimiter. Delimiters in string literals, quoted identifiers,
+// comments and dollar-quoted blocks of PostgreSQL are skipped. The delimiter can be changed by MySQL client style
+// `DELIMITER $$` directive line, which is removed from statements. Statements that only contain comments are dropped.
+func splitStatements(script string) []string {
+	var (
+		items     []string
+		delimiter = ";"
+		start     int
+		hasCode   bool
+	)
+
+	add := func(end int) {
+		if hasCode {
+			if it := strings.TrimSpace(script[start:end]); it != "" {
+				items = append(items, it)
+			}
+		}
+		hasCode = false
+	}
+
+	n := len(script)
+	for i := 0; i < n; {
+		c := script[i]
+
+		// DELIMITER directive should be the only content on its line
+		if !hasCode && isLineStart(script, i) && hasPrefixFold(script[i:], "DELIMITER ") {
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = n
+			} else {
+				end += i
+			}
+
+			if d := strings.TrimSpace(script[i+len("DELIMITER ") : end]); d != "" {
+				delimiter = d
+			}
+			i = end
+			start = i
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+			hasCode = true
+		case c == '-' && i+1 < n && script[i+1] == '-':
+			i = skipLine(script, i)
+		case c == '/' && i+1 < n && script[i+1] == '*':
+			i = skipBlockComment(script, i)
+		// delimiter is checked before dollar quote, eg `DELIMITER $$` on MySQL
+		case strings.HasPrefix(script[i:], delimiter):
+			add(i)
+			i += len(delimiter)
+			start = i
+		case c == '$' && (i == 0 || !isIdentChar(script[i-1])):
+			tag, ok := getDollarTag(script[i:])
+			if !ok {
+				i++
+				hasCode = true
+				continue
+			}
+
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				i = n
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+			hasCode = true
+		default:
+			if !isSpace(c) {
+				hasCode = true
+			}
+			i++
+		}
+	}
+
+	add(n)
+
+	return items
+}
+
+// skipQuoted returns the position after the quoted string that starts at i. The quote is escaped by doubling it
+// or by a backslash.
+func skipQuoted(s string, i int, quote byte) int {
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// skipLine returns the position of the line break of the line that contains i.
+func skipLine(s string, i int) int {
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return len(s)
+	}
+	return i + end
+}
+
+// skipBlockComment returns the position after the block comment that starts at i.
+func skipBlockComment(s string, i int) int {
+	end := strings.Index(s[i+2:], "*/")
+	if end < 0 {
+		return len(s)
+	}
+	return i + end + 4
+}
+
+// getDollarTag returns the dollar quote tag at the beginning of s. eg `$$` or `$body$`.
+func getDollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '$' {
+			return s[0 : i+1], true
+		}
+
+		if !isIdentChar(c) || (i == 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// isLineStart checks if there are only spaces before position i on its line.
+func isLineStart(s string, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		if s[j] == '\n' {
+			return true
+		}
+
+		if !isSpace(s[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[0:len(prefix)], prefix)
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,105 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "empty",
+			script: " \n ",
+		},
+		{
+			name:   "semicolon",
+			script: "CREATE TABLE a (id int);\n\nCREATE TABLE b (id int)",
+			want:   []string{"CREATE TABLE a (id int)", "CREATE TABLE b (id int)"},
+		},
+		{
+			name:   "quotes",
+			script: "INSERT INTO a VALUES('a;b', \"c;d\", 'it''s;', 'x\\';y');SELECT `a;b` FROM a;",
+			want:   []string{"INSERT INTO a VALUES('a;b', \"c;d\", 'it''s;', 'x\\';y')", "SELECT `a;b` FROM a"},
+		},
+		{
+			name:   "comments",
+			script: "/* rotate: monthly=20240201-20240401 */\nCREATE TABLE a (id int); -- drop; it\n/* b; */\n-- only comment;",
+			want:   []string{"/* rotate: monthly=20240201-20240401 */\nCREATE TABLE a (id int)"},
+		},
+		{
+			name: "delimiter",
+			script: `CREATE TABLE a (id int);
+DELIMITER $$
+CREATE TRIGGER a_bi BEFORE INSERT ON a FOR EACH ROW
+BEGIN
+  SET NEW.id = NEW.id + 1;
+END$$
+DELIMITER ;
+INSERT INTO a VALUES(1);`,
+			want: []string{
+				"CREATE TABLE a (id int)",
+				"CREATE TRIGGER a_bi BEFORE INSERT ON a FOR EACH ROW\nBEGIN\n  SET NEW.id = NEW.id + 1;\nEND",
+				"INSERT INTO a VALUES(1)",
+			},
+		},
+		{
+			name: "dollar_quote",
+			script: `CREATE FUNCTION inc(i int) RETURNS int AS $$
+BEGIN
+  RETURN i + 1;
+END;
+$$ LANGUAGE plpgsql;
+CREATE FUNCTION dec(i int) RETURNS int AS $body$ SELECT $1 - 1; $body$ LANGUAGE sql;
+SELECT inc($1);`,
+			want: []string{
+				"CREATE FUNCTION inc(i int) RETURNS int AS $$\nBEGIN\n  RETURN i + 1;\nEND;\n$$ LANGUAGE plpgsql",
+				"CREATE FUNCTION dec(i int) RETURNS int AS $body$ SELECT $1 - 1; $body$ LANGUAGE sql",
+				"SELECT inc($1)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, splitStatements(test.script))
+		})
+	}
+}
+
+func TestMigrateTrigger(t *testing.T) {
+	d, clean, err := createSqlite3()
+	defer clean()
+	require.NoError(t, err)
+	db := sqle.Open(d)
+
+	m := New(db)
+	require.NoError(t, m.Discover(fstest.MapFS{
+		"0.1.0/1_create_table_logs.sql": &fstest.MapFile{
+			Data: []byte(`/* rotate: monthly=20240201-20240301 */
+CREATE TABLE IF NOT EXISTS logs<rotate> (id int NOT NULL, msg varchar(45) NOT NULL, PRIMARY KEY (id));
+DELIMITER //
+CREATE TRIGGER IF NOT EXISTS logs<rotate>_ai AFTER INSERT ON logs<rotate>
+BEGIN
+  UPDATE logs<rotate> SET msg = msg || ';' WHERE id = NEW.id;
+END//
+DELIMITER ;
+INSERT INTO logs<rotate> (id, msg) VALUES (1, 'a;b');`),
+		},
+	}, WithModule("tests"), WithLogger(DiscardLogger)))
+	require.NoError(t, m.Init(context.TODO()))
+	require.NoError(t, m.Migrate(context.TODO()))
+
+	for _, rt := range []string{"", "_202402", "_202403"} {
+		var msg string
+		require.NoError(t, db.QueryRow("SELECT msg FROM logs"+rt+" WHERE id = 1").Scan(&msg))
+		require.Equal(t, "a;b;", msg)
+	}
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"context"
+	// skipcq: GSC-G501
+	"crypto/md5"
+	"fmt"
+	"strings"
+
+	"github.com/yaitoo/sqle"
+)
+
+type tenantKey struct{}
+
+// Tenant returns the tenant that a Go migration is executed for. It is empty if it isn't executed for a tenant.
+func Tenant(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+// hasTenants checks if tenants are configured by WithTenants or WithTenantQuery.
+func (m *Migrator) hasTenants() bool {
+	return len(m.tenants) > 0 || m.tenantQuery != ""
+}
+
+// getTenants returns tenants on db. Tenants are discovered by tenant query if it is set.
+func (m *Migrator) getTenants(ctx context.Context, db *sqle.DB) ([]string, error) {
+	if m.tenantQuery == "" {
+		return m.tenants, nil
+	}
+
+	rows, err := db.QueryContext(ctx, m.tenantQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var t string
+		err = rows.Scan(&t)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+
+	return tenants, rows.Err()
+}
+
+// forTenant returns a copy of Migrator that migrates versions for tenant. <tenant> in scripts is replaced with the
+// tenant name, and checksums are derived from the tenant, so every tenant is tracked in sqle_migrations separately.
+func (m *Migrator) forTenant(tenant string) *Migrator {
+	tm := *m
+	tm.tenant = tenant
+	tm.Versions = make([]Semver, len(m.Versions))
+	for i, v := range m.Versions {
+		migrations := make([]Migration, len(v.Migrations))
+		for j, mi := range v.Migrations {
+			mi.Scripts = strings.ReplaceAll(mi.Scripts, "<tenant>", tenant)
+			mi.DownScripts = strings.ReplaceAll(mi.DownScripts, "<tenant>", tenant)
+
+			// skipcq: GSC-G401, GO-S1023
+			h := md5.New()
+			h.Write([]byte(tenant + ":" + mi.Checksum))
+			mi.Checksum = fmt.Sprintf("%x", h.Sum(nil))
+
+			migrations[j] = mi
+		}
+		v.Migrations = migrations
+		tm.Versions[i] = v
+	}
+
+	return &tm
+}
+
+// eachTenant calls fn with m if tenants are not configured, otherwise it calls fn with a copy of m for every tenant
+// on db. ctx passed to fn carries the tenant for Go migrations.
+func (m *Migrator) eachTenant(ctx context.Context, db *sqle.DB, fn func(ctx context.Context, tm *Migrator) error) error {
+	if !m.hasTenants() {
+		return fn(ctx, m)
+	}
+
+	tenants, err := m.getTenants(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tenants {
+		err = fn(context.WithValue(ctx, tenantKey{}, t), m.forTenant(t))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateTenant migrates all discovered versions for tenant on all databases. It is used to onboard a new tenant,
+// which doesn't need to be returned by WithTenants or WithTenantQuery.
+func (m *Migrator) MigrateTenant(ctx context.Context, tenant string) error {
+	tm := m.forTenant(tenant)
+	ctx = context.WithValue(ctx, tenantKey{}, tenant)
+	for i, db := range m.dbs {
+		tm.emit(ctx, Event{Type: EventStarted, Action: "migrate", DB: i})
+
+		r := Report{DB: i}
+		err := m.withLock(ctx, db, func(ctx context.Context) error {
+			return tm.startMigrate(ctx, db, &r)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+)
+
+func TestMigrateTenants(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS <tenant>_roles (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+		"0.2.0/1_create_table_users.sql": &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS <tenant>_users (id int NOT NULL, PRIMARY KEY (id));`),
+		},
+	}
+
+	tableExists := func(t *testing.T, db *sqle.DB, name string) bool {
+		var n int
+		err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+		require.NoError(t, err)
+		return n > 0
+	}
+
+	t.Run("tenants_should_work", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+		db := sqle.Open(d)
+
+		m := New(db)
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithTenants("a", "b")))
+		require.NoError(t, m.Init(context.TODO()))
+
+		reports, err := m.MigrateWithReport(context.TODO())
+		require.NoError(t, err)
+		require.Len(t, reports[0].Applied, 4)
+		require.Equal(t, "a", reports[0].Applied[0].Tenant)
+		require.Equal(t, "b", reports[0].Applied[3].Tenant)
+
+		for _, name := range []string{"a_roles", "a_users", "b_roles", "b_users"} {
+			require.True(t, tableExists(t, db, name))
+		}
+
+		// tenants are tracked separately
+		reports, err = m.MigrateWithReport(context.TODO())
+		require.NoError(t, err)
+		require.Len(t, reports[0].Skipped, 4)
+
+		var n int
+		require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migrations WHERE tenant = 'a'").Scan(&n))
+		require.Equal(t, 2, n)
+
+		// onboard a new tenant
+		require.NoError(t, m.MigrateTenant(context.TODO(), "c"))
+		require.True(t, tableExists(t, db, "c_roles"))
+		require.True(t, tableExists(t, db, "c_users"))
+		require.False(t, tableExists(t, db, "_roles"))
+	})
+
+	t.Run("tenants_should_work_in_all_apis", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0.1.0/1_create_table_roles.sql": &fstest.MapFile{
+				Data: []byte(`CREATE TABLE IF NOT EXISTS <tenant>_roles (id int NOT NULL, PRIMARY KEY (id));`),
+			},
+			"0.1.0/1_create_table_roles.down.sql": &fstest.MapFile{
+				Data: []byte(`DROP TABLE IF EXISTS <tenant>_roles;`),
+			},
+			"0.2.0/1_create_table_users.sql": &fstest.MapFile{
+				Data: []byte(`CREATE TABLE IF NOT EXISTS <tenant>_users (id int NOT NULL, PRIMARY KEY (id));`),
+			},
+		}
+
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+		db := sqle.Open(d)
+
+		m := New(db)
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithTenants("a", "b")))
+		require.NoError(t, m.Init(context.TODO()))
+
+		steps, err := m.Plan(context.TODO())
+		require.NoError(t, err)
+		require.Len(t, steps, 4)
+		require.Equal(t, "a", steps[0].Tenant)
+		require.Equal(t, "b", steps[3].Tenant)
+		require.Equal(t, MigrationStatusNew, steps[0].Status)
+		require.Equal(t, "CREATE TABLE IF NOT EXISTS a_roles (id int NOT NULL, PRIMARY KEY (id));", steps[0].Statements[0].SQL)
+
+		require.NoError(t, m.Migrate(context.TODO()))
+
+		steps, err = m.Plan(context.TODO())
+		require.NoError(t, err)
+		for _, s := range steps {
+			require.Equal(t, MigrationStatusExecuted, s.Status)
+		}
+
+		require.NoError(t, m.Status(context.TODO()))
+		require.NoError(t, m.Verify(context.TODO()))
+
+		history, err := m.History(context.TODO())
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, "b", history[1].Tenant)
+		require.Len(t, history[1].Executed, 2)
+		require.Empty(t, history[1].Pending)
+
+		// modified script is verified for tenants
+		modified := New(db)
+		fsys["0.2.0/1_create_table_users.sql"] = &fstest.MapFile{
+			Data: []byte(`CREATE TABLE IF NOT EXISTS <tenant>_users (id int NOT NULL, name varchar(45), PRIMARY KEY (id));`),
+		}
+		require.NoError(t, modified.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithTenants("a", "b")))
+		require.ErrorIs(t, modified.Verify(context.TODO()), ErrMigrationModified)
+		require.NoError(t, modified.Repair(context.TODO()))
+		require.NoError(t, modified.Verify(context.TODO()))
+
+		// 0.2.0 has no down script
+		require.ErrorIs(t, m.Rollback(context.TODO(), "0.1.0"), ErrMissingDownScript)
+		require.True(t, tableExists(t, db, "a_users"))
+
+		require.NoError(t, m.Rollback(context.TODO(), "0.2.0"))
+		delete(fsys, "0.2.0/1_create_table_users.sql")
+		m = New(db)
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithTenants("a", "b")))
+		require.NoError(t, m.Rollback(context.TODO(), ""))
+		require.False(t, tableExists(t, db, "a_roles"))
+		require.False(t, tableExists(t, db, "b_roles"))
+
+		// baseline is recorded for every tenant
+		require.NoError(t, m.Baseline(context.TODO(), "0.1.0"))
+		var n int
+		require.NoError(t, db.QueryRow("SELECT count(*) FROM sqle_migrations WHERE version = '0.1.0' AND tenant IN ('a', 'b')").Scan(&n))
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("tenant_query_should_work", func(t *testing.T) {
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+		db := sqle.Open(d)
+
+		_, err = db.Exec("CREATE TABLE tenants (name varchar(45) NOT NULL)")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO tenants (name) VALUES ('x'), ('y')")
+		require.NoError(t, err)
+
+		m := New(db)
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithTenantQuery("SELECT name FROM tenants ORDER BY name")))
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Migrate(context.TODO()))
+
+		for _, name := range []string{"x_roles", "x_users", "y_roles", "y_users"} {
+			require.True(t, tableExists(t, db, name))
+		}
+	})
+
+	t.Run("go_migration_should_get_tenant", func(t *testing.T) {
+		saved := registry
+		registry = nil
+		t.Cleanup(func() {
+			registry = saved
+		})
+
+		var tenants []string
+		Register("tests", "0.2.0", 2, "seed_users", func(ctx context.Context, tx *sqle.Tx) error {
+			tenants = append(tenants, Tenant(ctx))
+			_, err := tx.ExecContext(ctx, "INSERT INTO "+Tenant(ctx)+"_users (id) VALUES (1)")
+			return err
+		})
+
+		d, clean, err := createSqlite3()
+		defer clean()
+		require.NoError(t, err)
+		db := sqle.Open(d)
+
+		m := New(db)
+		require.NoError(t, m.Discover(fsys, WithModule("tests"), WithLogger(DiscardLogger), WithTenants("a", "b")))
+		require.NoError(t, m.Init(context.TODO()))
+		require.NoError(t, m.Migrate(context.TODO()))
+		require.Equal(t, []string{"a", "b"}, tenants)
+	})
+}
@@ -0,0 +1,28 @@
+module github.com/yaitoo/sqle/otel
+
+go 1.20
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/stretchr/testify v1.9.0
+	github.com/yaitoo/sqle v1.6.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/iancoleman/strcase v0.3.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yaitoo/async v1.0.4 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// hooks are added in sqle v1.6.0. v1.6.0 must be tagged before otel/v1.6.0, because the replace
+// only works in this repository and is ignored by modules that require otel.
+replace github.com/yaitoo/sqle => ../
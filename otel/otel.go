@@ -0,0 +1,122 @@
+// Package otel traces queries, commands and transactions of sqle with OpenTelemetry. It is a separate module, so
+// sqle doesn't depend on OpenTelemetry.
+//
+//	db.AddHook(otel.NewHook())
+package otel
+
+import (
+	"context"
+
+	"github.com/yaitoo/sqle"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/yaitoo/sqle/otel"
+
+// Attribute keys of spans.
+const (
+	AttrQuery        = attribute.Key("db.query.text")
+	AttrIndex        = attribute.Key("sqle.db.index")
+	AttrRotate       = attribute.Key("sqle.rotate")
+	AttrDHT          = attribute.Key("sqle.dht")
+	AttrStmtCache    = attribute.Key("sqle.stmt.cache") // hit or miss
+	AttrRowsAffected = attribute.Key("sqle.rows_affected")
+)
+
+type spanKey struct{}
+
+// Hook creates a span per query, exec and transaction. It implements sqle.Hook.
+type Hook struct {
+	tracer    trace.Tracer
+	withQuery bool
+}
+
+// Option configures Hook.
+type Option func(h *Hook)
+
+// WithTracerProvider sets the TracerProvider of spans. The global TracerProvider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(h *Hook) {
+		h.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// WithoutQuery disables the db.query.text attribute, eg queries might contain sensitive literals.
+func WithoutQuery() Option {
+	return func(h *Hook) {
+		h.withQuery = false
+	}
+}
+
+// NewHook creates a Hook with options.
+func NewHook(options ...Option) *Hook {
+	h := &Hook{
+		withQuery: true,
+	}
+
+	for _, o := range options {
+		o(h)
+	}
+
+	if h.tracer == nil {
+		h.tracer = otel.GetTracerProvider().Tracer(instrumentationName)
+	}
+
+	return h
+}
+
+// Before starts a span named sqle.query, sqle.exec or sqle.tx.
+func (h *Hook) Before(ctx context.Context, e *sqle.HookEvent) context.Context {
+	attrs := []attribute.KeyValue{
+		AttrIndex.Int(e.Index),
+	}
+
+	if h.withQuery && e.Op != sqle.OpTx {
+		attrs = append(attrs, AttrQuery.String(e.Query))
+	}
+
+	if e.Rotate != "" {
+		attrs = append(attrs, AttrRotate.String(e.Rotate))
+	}
+
+	if e.DHT != "" {
+		attrs = append(attrs, AttrDHT.String(e.DHT))
+	}
+
+	ctx, span := h.tracer.Start(ctx, "sqle."+e.Op.String(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(e.Start),
+		trace.WithAttributes(attrs...))
+
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// After ends the span that is started by Before, and records the error if it fails.
+func (h *Hook) After(ctx context.Context, e *sqle.HookEvent) {
+	span, ok := ctx.Value(spanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+
+	if e.Prepared {
+		if e.StmtCached {
+			span.SetAttributes(AttrStmtCache.String("hit"))
+		} else {
+			span.SetAttributes(AttrStmtCache.String("miss"))
+		}
+	}
+
+	if e.Op == sqle.OpExec {
+		span.SetAttributes(AttrRowsAffected.Int64(e.RowsAffected))
+	}
+
+	if e.Err != nil {
+		span.RecordError(e.Err)
+		span.SetStatus(codes.Error, e.Err.Error())
+	}
+
+	span.End(trace.WithTimestamp(e.Start.Add(e.Duration)))
+}
@@ -0,0 +1,122 @@
+package otel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle"
+	"github.com/yaitoo/sqle/shardid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func getAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, it := range span.Attributes() {
+		if it.Key == key {
+			return it.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestHook(t *testing.T) {
+	d, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	d.SetMaxOpenConns(1)
+	defer d.Close()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	db := sqle.Open(d)
+	db.AddHook(NewHook(WithTracerProvider(tp)))
+
+	ctx := context.TODO()
+	_, err = db.ExecContext(ctx, "CREATE TABLE users (id int, PRIMARY KEY (id))")
+	require.NoError(t, err)
+
+	err = db.Transaction(ctx, nil, func(ctx context.Context, tx *sqle.Tx) error {
+		for i := 1; i <= 2; i++ {
+			_, err := tx.ExecContext(ctx, "INSERT INTO users (id) VALUES (?)", i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var n int
+	require.NoError(t, db.QueryRowContext(sqle.WithDHT(ctx, "users"), "SELECT count(*) FROM users WHERE id > ?", 0).Scan(&n))
+	require.Equal(t, 2, n)
+
+	id := shardid.Build(time.Now().UnixMilli(), 0, 0, shardid.DailyRotate, 0)
+	_, err = db.QueryBuilder(ctx, sqle.New().On(id).SQL("SELECT * FROM logs<rotate>"))
+	require.Error(t, err)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 6)
+
+	s := spans[0]
+	require.Equal(t, "sqle.exec", s.Name())
+	v, ok := getAttr(s, AttrQuery)
+	require.True(t, ok)
+	require.Equal(t, "CREATE TABLE users (id int, PRIMARY KEY (id))", v.AsString())
+	v, _ = getAttr(s, AttrIndex)
+	require.Equal(t, int64(0), v.AsInt64())
+	_, ok = getAttr(s, AttrStmtCache)
+	require.False(t, ok)
+
+	// queries in transaction are children of the transaction span
+	tx := spans[3]
+	require.Equal(t, "sqle.tx", tx.Name())
+	for i, s := range spans[1:3] {
+		require.Equal(t, "sqle.exec", s.Name())
+		require.Equal(t, tx.SpanContext().SpanID(), s.Parent().SpanID())
+		v, _ = getAttr(s, AttrRowsAffected)
+		require.Equal(t, int64(1), v.AsInt64())
+		v, _ = getAttr(s, AttrStmtCache)
+		if i == 0 {
+			require.Equal(t, "miss", v.AsString())
+		} else {
+			require.Equal(t, "hit", v.AsString())
+		}
+	}
+	require.False(t, tx.StartTime().After(spans[1].StartTime()))
+	require.False(t, tx.EndTime().Before(spans[2].EndTime()))
+
+	s = spans[4]
+	require.Equal(t, "sqle.query", s.Name())
+	v, _ = getAttr(s, AttrDHT)
+	require.Equal(t, "users", v.AsString())
+
+	s = spans[5]
+	v, _ = getAttr(s, AttrRotate)
+	require.Equal(t, id.RotateName(), v.AsString())
+	require.Equal(t, codes.Error, s.Status().Code)
+	require.Len(t, s.Events(), 1)
+
+	// queries are not recorded WithoutQuery
+	sr = tracetest.NewSpanRecorder()
+	tp = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	h := NewHook(WithTracerProvider(tp), WithoutQuery())
+	e := &sqle.HookEvent{Op: sqle.OpExec, Query: "DELETE FROM users", Start: time.Now(), Err: errors.New("failed")}
+	h.After(h.Before(ctx, e), e)
+
+	spans = sr.Ended()
+	require.Len(t, spans, 1)
+	_, ok = getAttr(spans[0], AttrQuery)
+	require.False(t, ok)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+
+	// After without Before is ignored
+	h.After(ctx, e)
+	require.Len(t, sr.Ended(), 1)
+}
@@ -0,0 +1,442 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yaitoo/sqle/shardid"
+)
+
+var (
+	ErrRebalanceMismatch = errors.New("sqle: rebalance rows mismatch")
+	ErrMissingKeyColumn  = errors.New("sqle: missing key column")
+	ErrNonUniqueID       = errors.New("sqle: rebalance id is not unique")
+)
+
+// RebalanceTable describes the columns of a table that is rebalanced.
+type RebalanceTable struct {
+	Key string // column whose value is the DHT key of the row, eg "user_id". it can be non-unique
+	ID  string // column that is unique on each database, rows are paged, moved and deleted by it. it is "id" if it is empty
+}
+
+// Rebalance configures how data items of a DHT are moved once databases are added to it.
+type Rebalance struct {
+	Tables    map[string]RebalanceTable // table name => its columns, eg "orders": {Key: "user_id", ID: "id"}
+	BatchSize int                       // rows scanned in each batch, it is 500 if it is 0
+	Progress  string                    // progress table on each affected database, it is "sqle_rebalance" if it is empty
+
+	// Use applies quote and parameterize options on statements. eg UsePostgres
+	Use func(b *Builder)
+
+	// OnBatch is called after rows of a batch are moved. it should not block.
+	OnBatch func(e RebalanceEvent)
+}
+
+// RebalanceEvent is passed to Rebalance.OnBatch when a batch is finished.
+type RebalanceEvent struct {
+	DHT    string
+	Table  string
+	Index  int    // index of the affected database that rows are moved from
+	Moved  int64  // rows that have been moved from Index in Table
+	LastID string // id of the last row that has been scanned
+}
+
+// rebalanceProgress is the position of a table on an affected database.
+type rebalanceProgress struct {
+	lastID string
+	hasID  bool
+	moved  int64
+	done   bool
+}
+
+// Rebalance moves rows that are owned by affected virtual nodes of the DHT name from affected databases to the added
+// databases in batches, and calls DHT.Done once all rows are moved. Rows are paged, moved and deleted by the ID column
+// of their table, so the Key column can be non-unique. IDs only need to be unique on each database, rows of different
+// databases are told apart by their keys on the added database. Rows are inserted into the new database and their count
+// is verified before they are deleted from the old one, so a batch is moved again safely if it is interrupted.
+// Position of each table is saved in the progress table of the affected database, and Rebalance resumes from it after
+// a crash as long as the same databases are added to the DHT again. The progress table is cleaned before DHT.Done is
+// called.
+func (db *DB) Rebalance(ctx context.Context, name string, rb Rebalance) error {
+	dht := db.GetDHT(name)
+	if dht == nil {
+		return ErrMissingDHT
+	}
+
+	if rb.BatchSize <= 0 {
+		rb.BatchSize = 500
+	}
+
+	if rb.Progress == "" {
+		rb.Progress = "sqle_rebalance"
+	}
+
+	tables := make([]string, 0, len(rb.Tables))
+	for t := range rb.Tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+
+	affected := dht.Affected()
+	for _, i := range affected {
+		c, err := db.getClient(i)
+		if err != nil {
+			return err
+		}
+
+		if err := rb.createProgress(ctx, c); err != nil {
+			return err
+		}
+
+		for _, t := range tables {
+			if err := db.rebalanceTable(ctx, dht, name, c, t, rb); err != nil {
+				return fmt.Errorf("sqle: rebalance %s on db-%d: %w", t, i, err)
+			}
+		}
+	}
+
+	for _, i := range affected {
+		c, err := db.getClient(i)
+		if err != nil {
+			return err
+		}
+
+		b := rb.builder()
+		b.Delete(rb.Progress).Where("dht = {dht}").Param("dht", name)
+		if _, err := c.ExecBuilder(ctx, b); err != nil {
+			return err
+		}
+	}
+
+	dht.Done()
+
+	return nil
+}
+
+// getClient returns the Client of index, or ErrInvalidIndex if it doesn't exist.
+func (db *DB) getClient(index int) (*Client, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if index < 0 || index >= len(db.dbs) {
+		return nil, ErrInvalidIndex
+	}
+
+	return db.dbs[index], nil
+}
+
+// rebalanceTable scans table on src in order of its id column from the saved position, and moves rows whose keys
+// are busy to their next databases batch by batch.
+func (db *DB) rebalanceTable(ctx context.Context, dht *shardid.DHT, name string, src *Client, table string,
+	rb Rebalance) error {
+	key := rb.Tables[table].Key
+	id := rb.Tables[table].ID
+	if id == "" {
+		id = "id"
+	}
+
+	p, err := rb.loadProgress(ctx, src, name, table)
+	if err != nil {
+		return err
+	}
+
+	for !p.done {
+		cols, items, err := rb.scan(ctx, src, table, id, p)
+		if err != nil {
+			return err
+		}
+
+		k := findColumn(cols, key)
+		n := findColumn(cols, id)
+		if k < 0 || n < 0 {
+			return ErrMissingKeyColumn
+		}
+
+		moving := make(map[int][][]any)
+		var ids []any
+		for i, it := range items {
+			// rows are ordered by id, a duplicated id is next to each other
+			if i > 0 && rebalanceKey(items[i-1][n]) == rebalanceKey(it[n]) {
+				return ErrNonUniqueID
+			}
+
+			cur, next, err := dht.On(rebalanceKey(it[k]))
+			if !errors.Is(err, shardid.ErrDataItemIsBusy) || cur != src.Index {
+				continue
+			}
+
+			moving[next] = append(moving[next], it)
+			ids = append(ids, it[n])
+		}
+
+		for i, rows := range moving {
+			dest, err := db.getClient(i)
+			if err != nil {
+				return err
+			}
+
+			if err := rb.insert(ctx, dest, table, key, id, k, n, cols, rows); err != nil {
+				return err
+			}
+		}
+
+		if len(items) > 0 {
+			p.lastID = rebalanceKey(items[len(items)-1][n])
+			p.hasID = true
+		}
+		p.moved += int64(len(ids))
+		p.done = len(items) < rb.BatchSize
+
+		err = src.Transaction(ctx, nil, func(ctx context.Context, tx *Tx) error {
+			if len(ids) > 0 {
+				b := rb.builder()
+				b.Delete(table)
+				rb.whereIn(b, id, ids)
+
+				result, err := tx.ExecBuilder(ctx, b)
+				if err != nil {
+					return err
+				}
+
+				affected, err := result.RowsAffected()
+				if err != nil {
+					return err
+				}
+
+				if affected != int64(len(ids)) {
+					return ErrRebalanceMismatch
+				}
+			}
+
+			return rb.saveProgress(ctx, tx, name, table, p)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if rb.OnBatch != nil {
+			rb.OnBatch(RebalanceEvent{DHT: name, Table: table, Index: src.Index, Moved: p.moved, LastID: p.lastID})
+		}
+	}
+
+	return nil
+}
+
+// scan reads a batch of rows after the saved position from table on primary.
+func (rb *Rebalance) scan(ctx context.Context, c *Client, table, id string, p rebalanceProgress) ([]string, [][]any, error) {
+	b := rb.builder()
+	b.Select(table).
+		If(p.hasID).SQL(" WHERE " + b.quoteColumn(id) + " > {last}").
+		SQL(" ORDER BY " + b.quoteColumn(id) + " LIMIT " + strconv.Itoa(rb.BatchSize))
+
+	if p.hasID {
+		b.Param("last", p.lastID)
+	}
+
+	rows, err := c.QueryBuilder(WithPrimary(ctx), b)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var items [][]any
+	for rows.Next() {
+		values := make([]any, len(cols))
+		dest := make([]any, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+
+		items = append(items, values)
+	}
+
+	return cols, items, rows.Err()
+}
+
+// insert writes rows into table on dest in a transaction, and verifies their count. Rows with the same ids and keys
+// are deleted first, they are left by an interrupted batch. Keys of rows are owned by the source database only, so
+// rows that are moved from other databases with overlapping ids are neither deleted nor counted.
+func (rb *Rebalance) insert(ctx context.Context, dest *Client, table, key, id string, k, n int, cols []string,
+	rows [][]any) error {
+	ids := make([]any, 0, len(rows))
+	keys := make([]any, 0, len(rows))
+	for _, it := range rows {
+		ids = append(ids, it[n])
+		keys = append(keys, it[k])
+	}
+
+	return dest.Transaction(ctx, nil, func(ctx context.Context, tx *Tx) error {
+		b := rb.builder()
+		b.Delete(table)
+		rb.whereIn(b, id, ids)
+		rb.andIn(b, key, keys)
+		if _, err := tx.ExecBuilder(ctx, b); err != nil {
+			return err
+		}
+
+		for _, it := range rows {
+			ib := rb.builder().Insert(table)
+			for i, c := range cols {
+				ib.Set(c, it[i])
+			}
+
+			if _, err := tx.ExecBuilder(ctx, ib.End()); err != nil {
+				return err
+			}
+		}
+
+		b = rb.builder()
+		b.SQL("SELECT COUNT(*) FROM " + b.Quote + table + b.Quote)
+		rb.whereIn(b, id, ids)
+		rb.andIn(b, key, keys)
+
+		var count int
+		if err := tx.QueryRowBuilder(ctx, b).Scan(&count); err != nil {
+			return err
+		}
+
+		if count != len(rows) {
+			return ErrRebalanceMismatch
+		}
+
+		return nil
+	})
+}
+
+// createProgress creates the progress table on c if it doesn't exist.
+func (rb *Rebalance) createProgress(ctx context.Context, c *Client) error {
+	b := rb.builder()
+	b.SQL("CREATE TABLE IF NOT EXISTS " + b.Quote + rb.Progress + b.Quote + ` (
+  dht varchar(64) NOT NULL,
+  tbl varchar(128) NOT NULL,
+  last_id varchar(255) NOT NULL,
+  moved bigint NOT NULL,
+  done int NOT NULL,
+  PRIMARY KEY (dht, tbl)
+)`)
+
+	_, err := c.ExecBuilder(ctx, b)
+	return err
+}
+
+// loadProgress returns the saved position of table, it starts from the first row if there is no saved position.
+func (rb *Rebalance) loadProgress(ctx context.Context, c *Client, name, table string) (rebalanceProgress, error) {
+	var (
+		p    rebalanceProgress
+		done int
+	)
+
+	b := rb.builder()
+	b.Select(rb.Progress, "last_id", "moved", "done").
+		Where("dht = {dht} AND tbl = {tbl}").
+		Param("dht", name).
+		Param("tbl", table)
+
+	err := c.QueryRowBuilder(WithPrimary(ctx), b).Scan(&p.lastID, &p.moved, &done)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, nil
+		}
+		return p, err
+	}
+
+	p.hasID = true
+	p.done = done == 1
+
+	return p, nil
+}
+
+// saveProgress saves the position of table in tx.
+func (rb *Rebalance) saveProgress(ctx context.Context, tx *Tx, name, table string, p rebalanceProgress) error {
+	b := rb.builder()
+	b.Delete(rb.Progress).Where("dht = {dht} AND tbl = {tbl}").
+		Param("dht", name).
+		Param("tbl", table)
+
+	if _, err := tx.ExecBuilder(ctx, b); err != nil {
+		return err
+	}
+
+	done := 0
+	if p.done {
+		done = 1
+	}
+
+	b = rb.builder()
+	b.Insert(rb.Progress).
+		Set("dht", name).
+		Set("tbl", table).
+		Set("last_id", p.lastID).
+		Set("moved", p.moved).
+		Set("done", done).
+		End()
+
+	_, err := tx.ExecBuilder(ctx, b)
+	return err
+}
+
+// whereIn appends a WHERE clause that matches column with values.
+func (rb *Rebalance) whereIn(b *Builder, column string, values []any) {
+	b.SQL(" WHERE ")
+	rb.in(b, column, "k", values)
+}
+
+// andIn appends an AND condition that matches column with values to the WHERE clause of whereIn.
+func (rb *Rebalance) andIn(b *Builder, column string, values []any) {
+	b.SQL(" AND ")
+	rb.in(b, column, "v", values)
+}
+
+// in appends a condition that matches column with values, their parameters are named by prefix and positions.
+func (rb *Rebalance) in(b *Builder, column, prefix string, values []any) {
+	b.SQL(b.quoteColumn(column) + " IN (")
+	for i, v := range values {
+		n := prefix + strconv.Itoa(i)
+		if i > 0 {
+			b.SQL(", ")
+		}
+		b.SQL("{"+n+"}").Param(n, v)
+	}
+	b.SQL(")")
+}
+
+func (rb *Rebalance) builder() *Builder {
+	b := New()
+	if rb.Use != nil {
+		rb.Use(b)
+	}
+	return b
+}
+
+// findColumn returns the position of name in cols, or -1 if it is missing.
+func findColumn(cols []string, name string) int {
+	for i, c := range cols {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// rebalanceKey formats the value of key or id column as a string.
+func rebalanceKey(v any) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
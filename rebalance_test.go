@@ -0,0 +1,236 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/sqle/shardid"
+)
+
+func TestRebalance(t *testing.T) {
+	dir := t.TempDir()
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d, err := sql.Open("sqlite3", filepath.Join(dir, name+".db"))
+		require.NoError(t, err)
+		_, err = d.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name varchar(20))")
+		require.NoError(t, err)
+		return d
+	}
+
+	count := func(t *testing.T, c *Client, query string, args ...any) int {
+		var n int
+		require.NoError(t, c.QueryRowContext(WithPrimary(context.TODO()), query, args...).Scan(&n))
+		return n
+	}
+
+	db := Open(createDB(t, "db0"), createDB(t, "db1"))
+	db.NewDHT("users", 0, 1)
+
+	const total = 200
+	for i := 1; i <= total; i++ {
+		c, err := db.OnDHT(strconv.Itoa(i), "users")
+		require.NoError(t, err)
+		_, err = c.Exec("INSERT INTO users (id, name) VALUES (?, ?)", i, "user"+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+
+	require.ErrorIs(t, db.Rebalance(context.TODO(), "missing", Rebalance{}), ErrMissingDHT)
+
+	db.Add(createDB(t, "db2"))
+	dht := db.GetDHT("users")
+	affected := dht.Add(2)
+	require.NotEmpty(t, affected)
+	require.Equal(t, affected, dht.Affected())
+
+	var busy []string
+	for i := 1; i <= total; i++ {
+		_, next, err := dht.On(strconv.Itoa(i))
+		if err != nil {
+			require.ErrorIs(t, err, shardid.ErrDataItemIsBusy)
+			require.Equal(t, 2, next)
+			busy = append(busy, strconv.Itoa(i))
+		}
+	}
+	require.NotEmpty(t, busy)
+
+	// an interrupted batch left a copy on the new database
+	_, err := db.dbs[2].Exec("INSERT INTO users (id, name) VALUES (?, ?)", busy[0], "stale")
+	require.NoError(t, err)
+
+	// it is interrupted after the first batch
+	ctx, cancel := context.WithCancel(context.TODO())
+	var events []RebalanceEvent
+	rb := Rebalance{
+		Tables:    map[string]RebalanceTable{"users": {Key: "id"}},
+		BatchSize: 7,
+		OnBatch: func(e RebalanceEvent) {
+			events = append(events, e)
+			cancel()
+		},
+	}
+
+	require.ErrorIs(t, db.Rebalance(ctx, "users", rb), context.Canceled)
+	require.Len(t, events, 1)
+	require.Equal(t, "users", events[0].DHT)
+	require.Equal(t, "users", events[0].Table)
+
+	// progress is saved, and DHT is still busy
+	src := db.dbs[events[0].Index]
+	require.Equal(t, 1, count(t, src, "SELECT COUNT(*) FROM sqle_rebalance WHERE dht = ? AND tbl = ? AND done = ?", "users", "users", 0))
+	_, _, err = dht.On(busy[len(busy)-1])
+	require.ErrorIs(t, err, shardid.ErrDataItemIsBusy)
+
+	// it resumes from the saved progress
+	rb.OnBatch = func(e RebalanceEvent) {
+		events = append(events, e)
+	}
+	require.NoError(t, db.Rebalance(context.TODO(), "users", rb))
+	require.Equal(t, int64(len(busy)), events[len(events)-1].Moved)
+	require.Empty(t, dht.Affected())
+
+	for _, i := range affected {
+		require.Equal(t, 0, count(t, db.dbs[i], "SELECT COUNT(*) FROM sqle_rebalance"))
+	}
+
+	// all rows are on their databases
+	n := 0
+	for i := 0; i < 3; i++ {
+		n += count(t, db.dbs[i], "SELECT COUNT(*) FROM users")
+	}
+	require.Equal(t, total, n)
+
+	for i := 1; i <= total; i++ {
+		c, err := db.OnDHT(strconv.Itoa(i), "users")
+		require.NoError(t, err)
+
+		var name string
+		require.NoError(t, c.QueryRowContext(WithPrimary(context.TODO()), "SELECT name FROM users WHERE id = ?", i).Scan(&name))
+		require.Equal(t, "user"+strconv.Itoa(i), name)
+	}
+	require.Equal(t, len(busy), count(t, db.dbs[2], "SELECT COUNT(*) FROM users"))
+
+	// it is a no-op once DHT is done
+	require.NoError(t, db.Rebalance(context.TODO(), "users", rb))
+}
+
+func TestRebalanceNonUniqueKey(t *testing.T) {
+	dir := t.TempDir()
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d, err := sql.Open("sqlite3", filepath.Join(dir, name+".db"))
+		require.NoError(t, err)
+		_, err = d.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id int, amount int)")
+		require.NoError(t, err)
+		return d
+	}
+
+	db := Open(createDB(t, "db0"), createDB(t, "db1"))
+	db.NewDHT("orders", 0, 1)
+
+	// 3 orders per user
+	const users = 60
+	id := 0
+	for u := 1; u <= users; u++ {
+		c, err := db.OnDHT(strconv.Itoa(u), "orders")
+		require.NoError(t, err)
+		for j := 0; j < 3; j++ {
+			id++
+			_, err = c.Exec("INSERT INTO orders (id, user_id, amount) VALUES (?, ?, ?)", id, u, j)
+			require.NoError(t, err)
+		}
+	}
+
+	db.Add(createDB(t, "db2"))
+	dht := db.GetDHT("orders")
+	dht.Add(2)
+
+	// rows can't be paged by a non-unique column
+	err := db.Rebalance(context.TODO(), "orders", Rebalance{
+		Tables:    map[string]RebalanceTable{"orders": {Key: "user_id", ID: "user_id"}},
+		BatchSize: 4,
+	})
+	require.ErrorIs(t, err, ErrNonUniqueID)
+	require.NotEmpty(t, dht.Affected())
+
+	err = db.Rebalance(context.TODO(), "orders", Rebalance{
+		Tables:    map[string]RebalanceTable{"orders": {Key: "user_id"}},
+		BatchSize: 4,
+	})
+	require.NoError(t, err)
+	require.Empty(t, dht.Affected())
+
+	total := 0
+	for i := 0; i < 3; i++ {
+		var n int
+		require.NoError(t, db.dbs[i].QueryRowContext(WithPrimary(context.TODO()), "SELECT COUNT(*) FROM orders").Scan(&n))
+		total += n
+	}
+	require.Equal(t, users*3, total)
+
+	for u := 1; u <= users; u++ {
+		c, err := db.OnDHT(strconv.Itoa(u), "orders")
+		require.NoError(t, err)
+
+		var n int
+		require.NoError(t, c.QueryRowContext(WithPrimary(context.TODO()), "SELECT COUNT(*) FROM orders WHERE user_id = ?", u).Scan(&n))
+		require.Equal(t, 3, n)
+	}
+}
+
+func TestRebalanceOverlappingIDs(t *testing.T) {
+	dir := t.TempDir()
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d, err := sql.Open("sqlite3", filepath.Join(dir, name+".db"))
+		require.NoError(t, err)
+		// id is auto-increment on each database, it is not unique across databases
+		_, err = d.Exec("CREATE TABLE orders (id int NOT NULL, user_id int NOT NULL, amount int)")
+		require.NoError(t, err)
+		return d
+	}
+
+	db := Open(createDB(t, "db0"), createDB(t, "db1"), createDB(t, "db2"), createDB(t, "db3"))
+	db.NewDHT("orders", 0, 1, 2, 3)
+
+	const users = 100
+	ids := make(map[int]int)
+	for u := 1; u <= users; u++ {
+		c, err := db.OnDHT(strconv.Itoa(u), "orders")
+		require.NoError(t, err)
+		for j := 0; j < 2; j++ {
+			ids[c.Index]++
+			_, err = c.Exec("INSERT INTO orders (id, user_id, amount) VALUES (?, ?, ?)", ids[c.Index], u, j)
+			require.NoError(t, err)
+		}
+	}
+
+	db.Add(createDB(t, "db4"))
+	dht := db.GetDHT("orders")
+	// rows are moved from many databases to the new one
+	require.Greater(t, len(dht.Add(4)), 1)
+
+	err := db.Rebalance(context.TODO(), "orders", Rebalance{
+		Tables:    map[string]RebalanceTable{"orders": {Key: "user_id"}},
+		BatchSize: 8,
+	})
+	require.NoError(t, err)
+
+	total := 0
+	for i := 0; i < 5; i++ {
+		var n int
+		require.NoError(t, db.dbs[i].QueryRowContext(WithPrimary(context.TODO()), "SELECT COUNT(*) FROM orders").Scan(&n))
+		total += n
+	}
+	require.Equal(t, users*2, total)
+
+	for u := 1; u <= users; u++ {
+		c, err := db.OnDHT(strconv.Itoa(u), "orders")
+		require.NoError(t, err)
+
+		var n int
+		require.NoError(t, c.QueryRowContext(WithPrimary(context.TODO()), "SELECT COUNT(*) FROM orders WHERE user_id = ?", u).Scan(&n))
+		require.Equal(t, 2, n)
+	}
+}
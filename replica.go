@@ -0,0 +1,140 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaPolicy is the policy that routes queries to read replicas of a Client.
+type ReplicaPolicy int
+
+const (
+	RoundRobin   ReplicaPolicy = iota // queries are routed to replicas in turn
+	LeastLatency                      // queries are routed to the replica that has the least average latency
+)
+
+// ReplicaErrorLatency is the latency that a failed query on a replica is observed as by LeastLatency, so a replica that
+// fails fast is not preferred.
+var ReplicaErrorLatency = time.Second
+
+type primaryContextKey struct{}
+
+// WithPrimary returns a copy of ctx that forces queries to be executed on primary, eg read-your-writes after a write.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// AddReplica adds read replicas of the database. Queries that are not in a transaction are routed to replicas, and
+// commands and transactions are executed on primary. Replicas share hooks and statement options with primary.
+func (db *Client) AddReplica(dbs ...*sql.DB) {
+	db.stmtsMutex.Lock()
+	maxIdleTime, maxSize := db.stmtMaxIdleTime, db.stmtMaxSize
+	db.stmtsMutex.Unlock()
+
+	db.replicasMu.Lock()
+	defer db.replicasMu.Unlock()
+
+	replicas := make([]*Client, 0, len(db.replicas)+len(dbs))
+	replicas = append(replicas, db.replicas...)
+	for _, d := range dbs {
+		r := newClient(d, db.Index, db.hooks, maxIdleTime, maxSize)
+		r.replica = len(replicas) + 1
+		replicas = append(replicas, r)
+	}
+
+	db.replicas = replicas
+}
+
+// SetReplicaPolicy sets the policy that routes queries to read replicas. It is RoundRobin by default.
+func (db *Client) SetReplicaPolicy(p ReplicaPolicy) {
+	db.replicasMu.Lock()
+	defer db.replicasMu.Unlock()
+
+	db.replicaPolicy = p
+}
+
+func (db *Client) getReplicas() []*Client {
+	db.replicasMu.RLock()
+	defer db.replicasMu.RUnlock()
+
+	return db.replicas
+}
+
+// reader returns a healthy replica to execute query on, primary is returned if there is no healthy replica or
+// WithPrimary is set.
+func (db *Client) reader(ctx context.Context) *Client {
+	db.replicasMu.RLock()
+	replicas, policy := db.replicas, db.replicaPolicy
+	db.replicasMu.RUnlock()
+
+	if len(replicas) == 0 {
+		return db
+	}
+
+	if primary, _ := ctx.Value(primaryContextKey{}).(bool); primary {
+		return db
+	}
+
+	for _, r := range replicas {
+		if r.Health() == Unhealthy {
+			replicas = getHealthyReplicas(replicas)
+			break
+		}
+	}
+
+	if len(replicas) == 0 {
+		return db
+	}
+
+	if policy == LeastLatency {
+		r := replicas[0]
+		least := atomic.LoadInt64(&r.latency)
+		for _, it := range replicas[1:] {
+			n := atomic.LoadInt64(&it.latency)
+			if n < least {
+				r, least = it, n
+			}
+		}
+		return r
+	}
+
+	n := atomic.AddUint32(&db.next, 1)
+	return replicas[int(n-1)%len(replicas)]
+}
+
+// getHealthyReplicas returns replicas that are not Unhealthy.
+func getHealthyReplicas(replicas []*Client) []*Client {
+	items := make([]*Client, 0, len(replicas))
+	for _, r := range replicas {
+		if r.Health() != Unhealthy {
+			items = append(items, r)
+		}
+	}
+	return items
+}
+
+// observe updates the moving average latency of the replica with d, the one that hasn't been used is chosen first by
+// LeastLatency. A failed query is observed as ReplicaErrorLatency at least.
+func (db *Client) observe(d time.Duration, err error) {
+	if db.replica == 0 {
+		return
+	}
+
+	if err != nil && d < ReplicaErrorLatency {
+		d = ReplicaErrorLatency
+	}
+
+	for {
+		old := atomic.LoadInt64(&db.latency)
+		n := int64(d)
+		if old > 0 {
+			n = old + (n-old)/8
+		}
+
+		if atomic.CompareAndSwapInt64(&db.latency, old, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package sqle
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplica(t *testing.T) {
+	createDB := func(t *testing.T, name string) *sql.DB {
+		d := createSQLite3()
+		d.SetMaxOpenConns(1)
+		_, err := d.Exec("CREATE TABLE nodes (name varchar(10))")
+		require.NoError(t, err)
+		_, err = d.Exec("INSERT INTO nodes (name) VALUES (?)", name)
+		require.NoError(t, err)
+		return d
+	}
+
+	getNode := func(t *testing.T, ctx context.Context, c Connector) string {
+		var name string
+		require.NoError(t, c.QueryRowContext(ctx, "SELECT name FROM nodes WHERE name <> ?", "").Scan(&name))
+		return name
+	}
+
+	t.Run("round_robin_should_work", func(t *testing.T) {
+		db := Open(createDB(t, "primary"))
+		db.AddReplica(0, createDB(t, "r1"), createDB(t, "r2"))
+
+		ctx := context.TODO()
+		require.Equal(t, "r1", getNode(t, ctx, db))
+		require.Equal(t, "r2", getNode(t, ctx, db))
+		require.Equal(t, "r1", getNode(t, ctx, db))
+
+		rows, err := db.QueryContext(ctx, "SELECT name FROM nodes")
+		require.NoError(t, err)
+		var name string
+		require.True(t, rows.Next())
+		require.NoError(t, rows.Scan(&name))
+		require.NoError(t, rows.Close())
+		require.Equal(t, "r2", name)
+
+		// read-your-writes
+		require.Equal(t, "primary", getNode(t, WithPrimary(ctx), db))
+
+		// commands and transactions are executed on primary
+		_, err = db.ExecContext(ctx, "UPDATE nodes SET name = ?", "main")
+		require.NoError(t, err)
+		require.Equal(t, "main", getNode(t, WithPrimary(ctx), db))
+
+		err = db.Transaction(ctx, nil, func(ctx context.Context, tx *Tx) error {
+			require.Equal(t, "main", getNode(t, ctx, tx))
+			return nil
+		})
+		require.NoError(t, err)
+
+		// statement options are applied on replicas
+		db.SetStmtMaxSize(10)
+		for _, r := range db.Client.getReplicas() {
+			require.Equal(t, 10, r.stmtMaxSize)
+			require.Equal(t, 1, r.StmtStats().Open)
+		}
+	})
+
+	t.Run("least_latency_should_work", func(t *testing.T) {
+		db := Open(createDB(t, "primary"))
+		db.SetReplicaPolicy(LeastLatency)
+		db.AddReplica(0, createDB(t, "r1"), createDB(t, "r2"))
+
+		ctx := context.TODO()
+		// replicas that haven't been used are chosen first
+		require.Equal(t, "r1", getNode(t, ctx, db))
+		require.Equal(t, "r2", getNode(t, ctx, db))
+
+		replicas := db.Client.getReplicas()
+		atomic.StoreInt64(&replicas[0].latency, int64(time.Second))
+		atomic.StoreInt64(&replicas[1].latency, int64(time.Millisecond))
+		require.Equal(t, "r2", getNode(t, ctx, db))
+		require.Equal(t, "r2", getNode(t, ctx, db))
+
+		// latency is a moving average
+		require.Less(t, atomic.LoadInt64(&replicas[1].latency), int64(time.Millisecond))
+
+		// a replica that fails fast is not preferred
+		require.NoError(t, replicas[1].DB.Close())
+		_, err := db.Query("SELECT name FROM nodes WHERE name <> ?", "")
+		require.Error(t, err)
+		require.GreaterOrEqual(t, atomic.LoadInt64(&replicas[1].latency), int64(ReplicaErrorLatency)/8)
+		atomic.StoreInt64(&replicas[0].latency, int64(10*time.Millisecond))
+		require.Equal(t, "r1", getNode(t, ctx, db))
+	})
+
+	t.Run("hook_should_get_replica", func(t *testing.T) {
+		db := Open(createDB(t, "primary"))
+		db.AddReplica(0, createDB(t, "r1"))
+
+		var calls []string
+		h := &recordHook{name: "a", calls: &calls}
+		db.AddHook(h)
+
+		require.Equal(t, "r1", getNode(t, context.TODO(), db))
+		require.Equal(t, "primary", getNode(t, WithPrimary(context.TODO()), db))
+		require.Len(t, h.events, 2)
+		require.Equal(t, 1, h.events[0].Replica)
+		require.Equal(t, 0, h.events[1].Replica)
+	})
+}
@@ -21,6 +21,8 @@ type Row struct {
 	stmt  *Stmt
 	err   error
 	query string
+
+	release func() // it marks the query finished on its Client
 }
 
 func (r *Row) Close() error {
@@ -28,8 +30,15 @@ func (r *Row) Close() error {
 		return nil
 	}
 
+	// stmt is released once, it might be closed again by Bind or Scan
 	if r.stmt != nil {
 		r.stmt.Reuse()
+		r.stmt = nil
+	}
+
+	if r.release != nil {
+		r.release()
+		r.release = nil
 	}
 
 	if r.rows == nil {
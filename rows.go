@@ -9,11 +9,20 @@ type Rows struct {
 	*sql.Rows
 	stmt  *Stmt
 	query string
+
+	release func() // it marks the query finished on its Client
 }
 
 func (r *Rows) Close() error {
+	// stmt is released once, it might be closed again by Bind or Scan
 	if r.stmt != nil {
 		r.stmt.Reuse()
+		r.stmt = nil
+	}
+
+	if r.release != nil {
+		r.release()
+		r.release = nil
 	}
 
 	if r.Rows == nil {
@@ -74,12 +74,35 @@ func (m *DHT) Done() {
 	m.Lock()
 	defer m.Unlock()
 
+	if m.next == nil {
+		return
+	}
+
 	m.affectedDbs = nil
 	m.affectedVNodes = make(map[uint32]bool)
 	m.current = m.next
 	m.next = nil
 }
 
+// Affected returns the databases that own affected virtual nodes, their data items should be moved to the added
+// databases before Done is called. It is empty if there is no database being added.
+func (m *DHT) Affected() []int {
+	if m == nil {
+		return nil
+	}
+	m.RLock()
+	defer m.RUnlock()
+
+	var dbs []int
+	for _, i := range m.affectedDbs {
+		dbs = append(dbs, m.dbs[i])
+	}
+
+	slices.Sort(dbs)
+
+	return dbs
+}
+
 // Add dynamically add databases, and return affected database
 func (m *DHT) Add(dbs ...int) []int {
 	if m == nil {
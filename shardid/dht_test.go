@@ -58,6 +58,7 @@ func TestDHT(t *testing.T) {
 	}
 	require.Equal(t, vn, m.affectedVNodes)
 	require.Equal(t, []int{0}, m.affectedDbs)
+	require.Equal(t, []int{1}, m.Affected())
 
 	// vNode E0 is affected, but 1149 is unnecessary to move
 	cur, next, err := m.On("1149")
@@ -114,12 +115,19 @@ func TestDHT(t *testing.T) {
 	require.Nil(t, err) // > Q1 last node => E0!
 
 	m.Done()
+	require.Empty(t, m.Affected())
 
 	cur, next, err = m.On("E1")
 	require.Equal(t, 3, cur)
 	require.Equal(t, 3, next)
 	require.Nil(t, err)
 
+	// Done is ignored if there is no database being added
+	m.Done()
+	cur, _, err = m.On("E1")
+	require.Equal(t, 3, cur)
+	require.Nil(t, err)
+
 	cur, next, err = m.On("150")
 	require.Equal(t, 1, cur)
 	require.Equal(t, 1, next)
@@ -134,5 +142,6 @@ func TestNilDHT(t *testing.T) {
 	require.ErrorIs(t, err, ErrNilDHT)
 	d.Add(1)
 	d.Done()
+	require.Nil(t, d.Affected())
 
 }
@@ -9,6 +9,12 @@ type Tx struct {
 	*sql.Tx
 	noCopy //nolint
 	stmts  map[string]*sql.Stmt
+
+	index   int
+	hooks   *hooks
+	ctx     context.Context // context that is returned by hooks in BeginTx
+	event   *HookEvent
+	release func() // it marks the transaction finished on its Client
 }
 
 func (tx *Tx) prepareStmt(ctx context.Context, query string) (*sql.Stmt, error) {
@@ -17,6 +23,7 @@ func (tx *Tx) prepareStmt(ctx context.Context, query string) (*sql.Stmt, error)
 	}
 	s, ok := tx.stmts[query]
 	if ok {
+		setStmtCached(ctx, true)
 		return s, nil
 	}
 
@@ -26,6 +33,7 @@ func (tx *Tx) prepareStmt(ctx context.Context, query string) (*sql.Stmt, error)
 	}
 
 	tx.stmts[query] = s
+	setStmtCached(ctx, false)
 
 	return s, nil
 }
@@ -41,6 +49,7 @@ func (tx *Tx) Query(query string, args ...any) (*Rows, error) {
 }
 
 func (tx *Tx) QueryBuilder(ctx context.Context, b *Builder) (*Rows, error) {
+	ctx = withRotate(ctx, b)
 	query, args, err := b.Build()
 	if err != nil {
 		return nil, err
@@ -50,7 +59,13 @@ func (tx *Tx) QueryBuilder(ctx context.Context, b *Builder) (*Rows, error) {
 }
 
 func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	ctx, e := tx.hooks.before(ctx, HookEvent{Op: OpQuery, Index: tx.index, InTx: true, Query: query, Args: args})
+	rows, err := tx.queryContext(ctx, query, args...)
+	tx.hooks.after(ctx, e, nil, err)
+	return rows, err
+}
 
+func (tx *Tx) queryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
 	if len(args) > 0 {
 		stmt, err := tx.prepareStmt(ctx, query)
 		if err != nil {
@@ -77,6 +92,7 @@ func (tx *Tx) QueryRow(query string, args ...any) *Row {
 }
 
 func (tx *Tx) QueryRowBuilder(ctx context.Context, b *Builder) *Row {
+	ctx = withRotate(ctx, b)
 	query, args, err := b.Build()
 	if err != nil {
 		return &Row{
@@ -88,7 +104,13 @@ func (tx *Tx) QueryRowBuilder(ctx context.Context, b *Builder) *Row {
 }
 
 func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	ctx, e := tx.hooks.before(ctx, HookEvent{Op: OpQuery, Index: tx.index, InTx: true, Query: query, Args: args})
+	r := tx.queryRowContext(ctx, query, args...)
+	tx.hooks.after(ctx, e, nil, r.err)
+	return r
+}
 
+func (tx *Tx) queryRowContext(ctx context.Context, query string, args ...any) *Row {
 	if len(args) > 0 {
 		stmt, err := tx.prepareStmt(ctx, query)
 		if err != nil {
@@ -125,6 +147,7 @@ func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
 }
 
 func (tx *Tx) ExecBuilder(ctx context.Context, b *Builder) (sql.Result, error) {
+	ctx = withRotate(ctx, b)
 	query, args, err := b.Build()
 	if err != nil {
 		return nil, err
@@ -133,7 +156,13 @@ func (tx *Tx) ExecBuilder(ctx context.Context, b *Builder) (sql.Result, error) {
 }
 
 func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, e := tx.hooks.before(ctx, HookEvent{Op: OpExec, Index: tx.index, InTx: true, Query: query, Args: args})
+	result, err := tx.execContext(ctx, query, args...)
+	tx.hooks.after(ctx, e, result, err)
+	return result, err
+}
 
+func (tx *Tx) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	if len(args) > 0 {
 		stmt, err := tx.prepareStmt(ctx, query)
 		if err != nil {
@@ -143,15 +172,37 @@ func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.R
 		return stmt.ExecContext(ctx, args...)
 	}
 
-	return tx.Tx.ExecContext(context.Background(), query, args...)
+	return tx.Tx.ExecContext(ctx, query, args...)
 }
 
 func (tx *Tx) Rollback() error {
 	defer tx.closeStmts()
-	return tx.Tx.Rollback()
+	err := tx.Tx.Rollback()
+	tx.finish("ROLLBACK", err)
+	return err
 }
 
 func (tx *Tx) Commit() error {
 	defer tx.closeStmts()
-	return tx.Tx.Commit()
+	err := tx.Tx.Commit()
+	tx.finish("COMMIT", err)
+	return err
+}
+
+// finish calls After of hooks that are called by BeginTx, and releases it on its Client once the transaction is
+// committed or rolled back.
+func (tx *Tx) finish(query string, err error) {
+	if tx.release != nil {
+		tx.release()
+		tx.release = nil
+	}
+
+	e := tx.event
+	if e == nil {
+		return
+	}
+
+	tx.event = nil
+	e.Query = query
+	tx.hooks.after(tx.ctx, e, nil, err)
 }
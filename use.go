@@ -3,7 +3,7 @@ package sqle
 import "strconv"
 
 func UsePostgres(b *Builder) {
-	b.Quote = "`"
+	b.Quote = "\""
 	b.Parameterize = func(name string, index int) string {
 		return "$" + strconv.Itoa(index)
 	}
@@ -17,7 +17,7 @@ func UseMySQL(b *Builder) {
 }
 
 func UseOracle(b *Builder) {
-	b.Quote = "`"
+	b.Quote = "\""
 	b.Parameterize = func(name string, index int) string {
 		return ":" + name
 	}